@@ -5,14 +5,45 @@ import (
 	"os/exec"
 )
 
+// Default program paths.
+const (
+	DefaultMPG321 = "/usr/bin/mpg321"
+	DefaultFlite  = "/usr/bin/flite"
+)
+
+// Config locates the programs that make sound. Zero values take
+// defaults.
+type Config struct {
+	// MPG321 is the path of mpg321, for playing mp3 files.
+	MPG321 string
+	// Flite is the path of flite, for speech.
+	Flite string
+}
+
+// Audio plays sounds and speech.
+type Audio struct {
+	cfg Config
+}
+
+// New makes an Audio using the configured programs.
+func New(cfg Config) *Audio {
+	if cfg.MPG321 == "" {
+		cfg.MPG321 = DefaultMPG321
+	}
+	if cfg.Flite == "" {
+		cfg.Flite = DefaultFlite
+	}
+	return &Audio{cfg: cfg}
+}
+
 // Play plays a sound to completion.
-func Play(ctx context.Context, path string) error {
+func (a *Audio) Play(ctx context.Context, path string) error {
 	// TODO: get error data from stderr
-	return exec.CommandContext(ctx, "/usr/bin/mpg321", "-q", path).Run()
+	return exec.CommandContext(ctx, a.cfg.MPG321, "-q", path).Run()
 }
 
 // Say converts text to speech and plays to completion.
-func Say(ctx context.Context, txt string) error {
+func (a *Audio) Say(ctx context.Context, txt string) error {
 	// TODO: get error data from stderr
-	return exec.CommandContext(ctx, "/usr/bin/flite", "-voice", "awb", "-t", txt).Run()
+	return exec.CommandContext(ctx, a.cfg.Flite, "-voice", "awb", "-t", txt).Run()
 }
@@ -1,21 +1,25 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"syscall"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/bikeos/bosd/gps"
 	"github.com/bikeos/bosd/internal/bench"
 	"github.com/bikeos/bosd/internal/daemon"
 	"github.com/bikeos/bosd/internal/http"
 	"github.com/bikeos/bosd/internal/ingest"
+	"github.com/bikeos/bosd/ntp"
 )
 
 var (
@@ -26,9 +30,32 @@ var (
 	flagBenchDur        time.Duration
 	flagDataDir         string
 	flagDevGPS          string
+	flagGPSChecksum     string
+	flagGPSBaud         string
+	flagGPSDAddr        string
+	flagServeNMEA       string
+	flagServeGPSD       string
+	flagGPSProfile      string
+	flagGPSProtocol     string
+	flagGPSRate         int
+	flagGPSSystems      []string
+	flagGPSSentences    []string
 	flagHttpRootDirPath string
 	flagLogDirPath      string
+	flagGPSFilter       bool
 	flagSetTime         bool
+	flagSetClock        bool
+	flagNTPSHM          int
+	flagNTPDelay        time.Duration
+	flagSimRoute        string
+	flagSimSpeed        string
+	flagSimRate         int
+	flagSimNoise        float64
+	flagSimDropouts     float64
+	flagSimDropoutTime  time.Duration
+	flagSimColdStart    time.Duration
+	flagSimFast         bool
+	flagSimLoop         bool
 )
 
 func init() {
@@ -40,13 +67,35 @@ func init() {
 		Short: "creates a key with a given value",
 	}
 	gpsCmd.PersistentFlags().StringVar(&flagDevGPS, "device", "/dev/ttyACM0", "device to query")
+	gpsCmd.PersistentFlags().StringVar(&flagGPSChecksum, "checksum", "drop", "bad checksum policy: drop, pass, or strict")
+	gpsCmd.PersistentFlags().StringVar(&flagGPSBaud, "baud", "", "serial baud rate or auto; empty leaves the device as is")
+	gpsCmd.PersistentFlags().StringVar(&flagGPSDAddr, "gpsd", "", "read from gpsd at a host:port or socket path instead of the device")
+	addSimFlags(gpsCmd.PersistentFlags())
 	gpsTimeCmd := &cobra.Command{
 		Use:   "date",
 		Short: "gets date and time from GPS",
 		Run:   gpsTimeCommand,
 	}
 	gpsTimeCmd.Flags().BoolVar(&flagSetTime, "set", false, "set system time")
+	addNTPFlags(gpsTimeCmd.Flags())
 	gpsCmd.AddCommand(gpsTimeCmd)
+	gpsSkyCmd := &cobra.Command{
+		Use:   "sky",
+		Short: "lists satellites in view of the GPS",
+		Run:   gpsSkyCommand,
+	}
+	gpsCmd.AddCommand(gpsSkyCmd)
+	gpsConfigCmd := &cobra.Command{
+		Use:   "config",
+		Short: "configures the GPS receiver",
+		Run:   gpsConfigCommand,
+	}
+	gpsConfigCmd.Flags().StringVar(&flagGPSProfile, "profile", "", "receiver profile json; overrides other flags")
+	gpsConfigCmd.Flags().StringVar(&flagGPSProtocol, "protocol", "pmtk", "receiver protocol: pmtk or ubx")
+	gpsConfigCmd.Flags().IntVar(&flagGPSRate, "rate", 0, "fix rate in Hz: 1, 5, or 10")
+	gpsConfigCmd.Flags().StringSliceVar(&flagGPSSystems, "constellations", nil, "constellations to enable: gps, glonass, galileo, beidou")
+	gpsConfigCmd.Flags().StringSliceVar(&flagGPSSentences, "sentences", nil, "NMEA sentences to emit (e.g., RMC,GGA)")
+	gpsCmd.AddCommand(gpsConfigCmd)
 	rootCmd.AddCommand(gpsCmd)
 
 	daemonCmd := &cobra.Command{
@@ -54,8 +103,31 @@ func init() {
 		Short: "start the bikeOS daemon",
 		Run:   daemonCommand,
 	}
+	daemonCmd.Flags().StringVar(&flagGPSDAddr, "gpsd", "", "read from gpsd at a host:port or socket path instead of GPS devices")
+	daemonCmd.Flags().StringVar(&flagServeNMEA, "serve-nmea", "", "rebroadcast NMEA sentences at a host:port or socket path")
+	daemonCmd.Flags().StringVar(&flagServeGPSD, "serve-gpsd", "", "serve gpsd TPV reports at a host:port or socket path")
+	daemonCmd.Flags().BoolVar(&flagGPSFilter, "gps-filter", true, "drop GPS jumps and smooth positions for reports")
+	daemonCmd.Flags().BoolVar(&flagSetClock, "set-clock", true, "set the system clock from the first GPS fixes")
+	addNTPFlags(daemonCmd.Flags())
+	addSimFlags(daemonCmd.Flags())
 	rootCmd.AddCommand(daemonCmd)
 
+	configCmd := &cobra.Command{
+		Use:   "config <subcommand>",
+		Short: "inspects the daemon config file in the data directory",
+	}
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "show",
+		Short: "prints the daemon settings with defaults filled in",
+		Run:   configShowCommand,
+	})
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "validate",
+		Short: "checks the daemon config file",
+		Run:   configValidateCommand,
+	})
+	rootCmd.AddCommand(configCmd)
+
 	benchCmd := &cobra.Command{
 		Use:   "bench",
 		Short: "benchmark interfaces",
@@ -70,6 +142,7 @@ func init() {
 		Run:   ingestCommand,
 	}
 	ingestCmd.Flags().StringVar(&flagLogDirPath, "logdir", "", "directory for log data")
+	ingestCmd.Flags().BoolVar(&flagGPSFilter, "gps-filter", false, "drop GPS jumps and smooth positions")
 	rootCmd.AddCommand(ingestCmd)
 
 	httpCmd := &cobra.Command{
@@ -79,25 +152,193 @@ func init() {
 	}
 	httpCmd.Flags().StringVar(&flagLogDirPath, "logdir", "", "directory for log data")
 	httpCmd.Flags().StringVar(&flagHttpRootDirPath, "rootdir", "", "directory for http resources")
+	httpCmd.Flags().BoolVar(&flagGPSFilter, "gps-filter", false, "drop GPS jumps and smooth positions")
 	rootCmd.AddCommand(httpCmd)
 }
 
+// addSimFlags adds the flags for a synthetic GPS.
+func addSimFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&flagSimRoute, "sim-route", "", "simulate riding a GPX or GeoJSON route instead of reading a GPS")
+	fs.StringVar(&flagSimSpeed, "sim-speed", "5", "simulated speed in m/s, or meters:speed steps (e.g., 0:5,1000:8)")
+	fs.IntVar(&flagSimRate, "sim-rate", 1, "simulated fixes per second")
+	fs.Float64Var(&flagSimNoise, "sim-noise", 0, "simulated position error in meters")
+	fs.Float64Var(&flagSimDropouts, "sim-dropouts", 0, "chance per second of a simulated fix dropout")
+	fs.DurationVar(&flagSimDropoutTime, "sim-dropout-time", 10*time.Second, "duration of a simulated dropout")
+	fs.DurationVar(&flagSimColdStart, "sim-cold-start", 0, "simulated time to first fix")
+	fs.BoolVar(&flagSimFast, "sim-fast", false, "simulate as fast as possible instead of in real time")
+	fs.BoolVar(&flagSimLoop, "sim-loop", false, "restart the simulated route at its end")
+}
+
+// addNTPFlags adds the flags for feeding GPS time to an NTP daemon.
+func addNTPFlags(fs *pflag.FlagSet) {
+	fs.IntVar(&flagNTPSHM, "ntp-shm", -1, "feed GPS time to an NTP daemon through this SHM refclock unit; -1 disables")
+	fs.DurationVar(&flagNTPDelay, "ntp-delay", 0, "time from the start of a fix's second to the GPS sending it")
+}
+
+// ntpConfig is the NTP refclock from the flags; nil if disabled.
+func ntpConfig() *ntp.RefclockConfig {
+	if flagNTPSHM < 0 {
+		return nil
+	}
+	return &ntp.RefclockConfig{Unit: flagNTPSHM, Delay: flagNTPDelay}
+}
+
+// simConfig is the synthetic GPS from the flags; nil if not simulating.
+func simConfig() (*gps.SimConfig, error) {
+	if flagSimRoute == "" {
+		return nil, nil
+	}
+	route, err := gps.ReadRoute(flagSimRoute)
+	if err != nil {
+		return nil, err
+	}
+	speeds, err := gps.ParseSpeedProfile(flagSimSpeed)
+	if err != nil {
+		return nil, err
+	}
+	return &gps.SimConfig{
+		Route:       route,
+		Speeds:      speeds,
+		Rate:        flagSimRate,
+		Noise:       flagSimNoise,
+		Dropouts:    flagSimDropouts,
+		DropoutTime: flagSimDropoutTime,
+		ColdStart:   flagSimColdStart,
+		Realtime:    !flagSimFast,
+		Loop:        flagSimLoop,
+	}, nil
+}
+
+func gpsConfig() (cfg gps.Config, err error) {
+	if cfg.Checksum, err = gps.ParseChecksumPolicy(flagGPSChecksum); err != nil {
+		return cfg, err
+	}
+	switch flagGPSBaud {
+	case "":
+	case "auto":
+		cfg.Baud = gps.AutoBaud
+	default:
+		if cfg.Baud, err = strconv.Atoi(flagGPSBaud); err != nil {
+			return cfg, fmt.Errorf("bad baud rate %q", flagGPSBaud)
+		}
+	}
+	return cfg, nil
+}
+
+func openGPS() (*gps.GPS, error) {
+	if flagGPSDAddr != "" {
+		return gps.NewGPSD(flagGPSDAddr)
+	}
+	if sim, err := simConfig(); sim != nil || err != nil {
+		if err != nil {
+			return nil, err
+		}
+		return gps.NewSimGPS(*sim)
+	}
+	cfg, err := gpsConfig()
+	if err != nil {
+		return nil, err
+	}
+	g, err := gps.NewGPSWithConfig(flagDevGPS, cfg)
+	if err == nil && cfg.Baud == gps.AutoBaud {
+		log.Infof("gps: detected %d baud", g.Baud())
+	}
+	return g, err
+}
+
 func gpsTimeCommand(cmd *cobra.Command, args []string) {
-	g, err := gps.NewGPS(flagDevGPS)
+	g, err := openGPS()
 	fatalIf(err)
 	defer g.Close()
+	var rc *ntp.Refclock
+	if cfg := ntpConfig(); cfg != nil {
+		cfg.Baud = g.Baud()
+		rc, err = ntp.NewRefclock(*cfg)
+		fatalIf(err)
+		defer rc.Close()
+	}
+	printed := false
 	for msg := range g.NMEA() {
-		if t := msg.Fix(); !t.IsZero() {
+		if rc != nil {
+			rc.Update(msg)
+		}
+		if t := msg.Fix(); !printed && msg.Valid() && !t.IsZero() {
 			fmt.Println(t)
 			if flagSetTime {
 				setSysTime(t)
 			}
+			if rc == nil {
+				return
+			}
+			// Keep feeding the NTP daemon until interrupted.
+			log.Infof("feeding GPS time to NTP SHM unit %d", rc.Unit())
+			printed = true
+		}
+	}
+	panic("gps closed: " + g.Close().Error())
+}
+
+func gpsSkyCommand(cmd *cobra.Command, args []string) {
+	g, err := openGPS()
+	fatalIf(err)
+	defer g.Close()
+	sv, ready := gps.NewSkyView(), false
+	for msg := range g.NMEA() {
+		if sv.Update(msg) {
+			switch msg.Msg().(type) {
+			case *gps.GSV, *gps.SKY:
+				ready = true
+			}
+			continue
+		}
+		// Wait for the next fix so all constellations are reported.
+		switch msg.Msg().(type) {
+		case *gps.RMC, *gps.TPV:
+		default:
+			continue
+		}
+		if !ready {
+			continue
+		}
+		sky := sv.Sky()
+		if len(sky.Satellites) == 0 {
+			fmt.Println("no satellites in view")
 			return
 		}
+		fmt.Printf("mode: %d pdop: %g hdop: %g vdop: %g\n", sky.Mode, sky.PDOP, sky.HDOP, sky.VDOP)
+		for _, sat := range sky.Satellites {
+			active := ""
+			if sat.Active {
+				active = "*"
+			}
+			fmt.Printf("%s %3d: el %2d az %3d snr %2d %s\n",
+				sat.Talker, sat.PRN, sat.Elevation, sat.Azimuth, sat.SNR, active)
+		}
+		fmt.Printf("%d in view, %d tracked, %d active\n",
+			len(sky.Satellites), sky.Tracked(), sky.Active())
+		return
 	}
 	panic("gps closed: " + g.Close().Error())
 }
 
+func gpsConfigCommand(cmd *cobra.Command, args []string) {
+	cfg, err := gpsConfig()
+	fatalIf(err)
+	rc := gps.ReceiverConfig{
+		Protocol:  gps.Protocol(flagGPSProtocol),
+		Rate:      flagGPSRate,
+		Sentences: flagGPSSentences,
+	}
+	for _, c := range flagGPSSystems {
+		rc.Constellations = append(rc.Constellations, gps.Constellation(c))
+	}
+	if flagGPSProfile != "" {
+		rc, err = gps.ReadProfile(flagGPSProfile)
+		fatalIf(err)
+	}
+	fatalIf(gps.ConfigureDevice(flagDevGPS, cfg, rc))
+}
+
 func dataDirExec(dir string) {
 	bosd := filepath.Join(dir, "bosd-"+runtime.GOARCH)
 	if os.Args[0] == bosd {
@@ -116,12 +357,45 @@ func dataDirExec(dir string) {
 
 func daemonCommand(cmd *cobra.Command, args []string) {
 	dataDirExec(flagDataDir)
+	sim, err := simConfig()
+	fatalIf(err)
+	st, err := daemon.ReadSettings(flagDataDir)
+	fatalIf(err)
 	cfg := daemon.Config{
-		OutDirPath: flagDataDir,
+		OutDirPath:    flagDataDir,
+		GPSDAddr:      flagGPSDAddr,
+		NMEAServeAddr: flagServeNMEA,
+		GPSDServeAddr: flagServeGPSD,
+		GPSSim:        sim,
+		GPSFilter:     gpsFilter(),
+		SetClock:      flagSetClock,
+		NTP:           ntpConfig(),
+		Settings:      st,
 	}
 	fatalIf(daemon.Run(cfg))
 }
 
+func configShowCommand(cmd *cobra.Command, args []string) {
+	st, err := daemon.ReadSettings(flagDataDir)
+	fatalIf(err)
+	b, err := json.MarshalIndent(st, "", "\t")
+	fatalIf(err)
+	fmt.Println(string(b))
+}
+
+func configValidateCommand(cmd *cobra.Command, args []string) {
+	p := filepath.Join(flagDataDir, daemon.SettingsFile)
+	if _, err := daemon.ReadSettings(flagDataDir); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if _, err := os.Stat(p); os.IsNotExist(err) {
+		fmt.Printf("%s: not found; using defaults\n", p)
+		return
+	}
+	fmt.Printf("%s: ok\n", p)
+}
+
 func benchCommand(cmd *cobra.Command, args []string) {
 	fatalIf(bench.Run(flagBenchDur))
 }
@@ -131,7 +405,15 @@ func ingestCommand(cmd *cobra.Command, args []string) {
 	if len(logdir) == 0 {
 		logdir = filepath.Join(flagDataDir, "log")
 	}
-	fatalIf(ingest.Run(logdir))
+	fatalIf(ingest.Run(logdir, gpsFilter()))
+}
+
+// gpsFilter is the position filter selected by --gps-filter.
+func gpsFilter() *gps.FilterConfig {
+	if !flagGPSFilter {
+		return nil
+	}
+	return &gps.FilterConfig{}
 }
 
 func httpCommand(cmd *cobra.Command, args []string) {
@@ -139,6 +421,7 @@ func httpCommand(cmd *cobra.Command, args []string) {
 		ListenAddr: "localhost:8800",
 		RootDir:    flagHttpRootDirPath,
 		LogDir:     flagLogDirPath,
+		GPSFilter:  gpsFilter(),
 	}
 	if err := http.Serve(cfg); err != nil {
 		panic(err)
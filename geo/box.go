@@ -0,0 +1,102 @@
+package geo
+
+import (
+	"math"
+	"sort"
+)
+
+// Box is a range of latitudes and longitudes in degrees. A box with West
+// greater than East crosses the antimeridian.
+type Box struct {
+	South float64
+	West  float64
+	North float64
+	East  float64
+}
+
+// BoxOf is the smallest box holding the points, crossing the
+// antimeridian if that makes it narrower.
+func BoxOf(pts ...Point) Box {
+	if len(pts) == 0 {
+		return Box{}
+	}
+	b := Box{South: pts[0].Lat, North: pts[0].Lat}
+	lons := make([]float64, len(pts))
+	for i, p := range pts {
+		b.South, b.North = math.Min(b.South, p.Lat), math.Max(b.North, p.Lat)
+		lons[i] = wrap180(p.Lon)
+	}
+	// The box spans the circle but for the widest gap between points.
+	sort.Float64s(lons)
+	gap, west := lons[0]+360-lons[len(lons)-1], 0
+	for i := 1; i < len(lons); i++ {
+		if d := lons[i] - lons[i-1]; d > gap {
+			gap, west = d, i
+		}
+	}
+	b.West = lons[west]
+	b.East = lons[(west+len(lons)-1)%len(lons)]
+	return b
+}
+
+// Crosses is set if the box crosses the antimeridian.
+func (b Box) Crosses() bool { return b.West > b.East }
+
+// Width is the span of longitudes in degrees.
+func (b Box) Width() float64 {
+	if b.East-b.West >= 360 {
+		return 360
+	}
+	return wrap360(b.East - b.West)
+}
+
+// Contains is set if the point is in the box.
+func (b Box) Contains(p Point) bool {
+	return p.Lat >= b.South && p.Lat <= b.North && b.containsLon(p.Lon)
+}
+
+func (b Box) containsLon(lon float64) bool { return wrap360(lon-b.West) <= b.Width() }
+
+// Extend is the smallest box holding the box and the point.
+func (b Box) Extend(p Point) Box {
+	b.South, b.North = math.Min(b.South, p.Lat), math.Max(b.North, p.Lat)
+	if b.containsLon(p.Lon) {
+		return b
+	}
+	lon := wrap180(p.Lon)
+	if wrap360(b.West-lon) < wrap360(lon-b.East) {
+		b.West = lon
+	} else {
+		b.East = lon
+	}
+	return b
+}
+
+// Polygon is a closed ring of points; the last point joins the first.
+// Edges run straight in latitude and longitude, taking the short way
+// across the antimeridian, so a polygon must span less than 180° of
+// longitude.
+type Polygon []Point
+
+// Contains is set if the point is inside the polygon.
+func (pg Polygon) Contains(p Point) bool {
+	if len(pg) < 3 {
+		return false
+	}
+	// Measure longitudes from the first vertex to unwrap the
+	// antimeridian.
+	ref := pg[0].Lon
+	x := wrap180(p.Lon - ref)
+	in := false
+	for i, j := 0, len(pg)-1; i < len(pg); j, i = i, i+1 {
+		xi, yi := wrap180(pg[i].Lon-ref), pg[i].Lat
+		xj, yj := wrap180(pg[j].Lon-ref), pg[j].Lat
+		if (yi > p.Lat) != (yj > p.Lat) && x < xi+(p.Lat-yi)*(xj-xi)/(yj-yi) {
+			in = !in
+		}
+	}
+	return in
+}
+
+// Box is the bounding box of the polygon.
+func (pg Polygon) Box() Box { return BoxOf(pg...) }
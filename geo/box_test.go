@@ -0,0 +1,100 @@
+package geo
+
+import "testing"
+
+func TestBoxOf(t *testing.T) {
+	tts := []struct {
+		pts   []Point
+		want  Box
+		width float64
+	}{
+		{[]Point{{52, 0}, {48, 2}}, Box{48, 0, 52, 2}, 2},
+		{[]Point{{0, 179}, {1, -179}}, Box{0, 179, 1, -179}, 2},
+		{[]Point{{0, -179}, {1, 179}, {-1, 178}}, Box{-1, 178, 1, -179}, 3},
+		{[]Point{{0, 0}, {0, 120}, {0, -120}}, Box{0, -120, 0, 120}, 240},
+		{[]Point{{5, 10}}, Box{5, 10, 5, 10}, 0},
+	}
+	for i, tt := range tts {
+		b := BoxOf(tt.pts...)
+		if b != tt.want || b.Width() != tt.width {
+			t.Errorf("#%d: wanted %+v (%g wide), got %+v (%g wide)", i, tt.want, tt.width, b, b.Width())
+		}
+	}
+}
+
+func TestBoxContains(t *testing.T) {
+	tts := []struct {
+		b    Box
+		p    Point
+		want bool
+	}{
+		{Box{48, 0, 52, 2}, Point{50, 1}, true},
+		{Box{48, 0, 52, 2}, Point{50, 3}, false},
+		{Box{48, 0, 52, 2}, Point{53, 1}, false},
+		// Southern and western hemispheres.
+		{Box{-34, 151, -33, 152}, Point{-33.8568, 151.2153}, true},
+		{Box{-34, 151, -33, 152}, Point{33.8568, 151.2153}, false},
+		{Box{-24, -47, -23, -46}, Point{-23.55, -46.63}, true},
+		// Across the antimeridian.
+		{Box{-20, 177, -15, -178}, Point{-18, 179}, true},
+		{Box{-20, 177, -15, -178}, Point{-18, -179}, true},
+		{Box{-20, 177, -15, -178}, Point{-18, 180}, true},
+		{Box{-20, 177, -15, -178}, Point{-18, 0}, false},
+		{Box{-90, -180, 90, 180}, Point{0, 0}, true},
+	}
+	for i, tt := range tts {
+		if v := tt.b.Contains(tt.p); v != tt.want {
+			t.Errorf("#%d: %+v contains %v: wanted %v, got %v", i, tt.b, tt.p, tt.want, v)
+		}
+	}
+}
+
+func TestBoxExtend(t *testing.T) {
+	b := Box{0, 179, 1, 179}
+	b = b.Extend(Point{2, -179})
+	if want := (Box{0, 179, 2, -179}); b != want {
+		t.Fatalf("wanted %+v, got %+v", want, b)
+	}
+	b = b.Extend(Point{-1, 170})
+	if want := (Box{-1, 170, 2, -179}); b != want {
+		t.Fatalf("wanted %+v, got %+v", want, b)
+	}
+	if b.Extend(Point{0, 175}) != b {
+		t.Fatalf("expected point inside the box to keep it")
+	}
+}
+
+func TestPolygonContains(t *testing.T) {
+	square := Polygon{{0, 0}, {0, 10}, {10, 10}, {10, 0}}
+	// Fiji straddles the antimeridian.
+	fiji := Polygon{{-21, 176}, {-21, -178}, {-15, -178}, {-15, 176}}
+	// A concave L shape.
+	ell := Polygon{{0, 0}, {0, 10}, {5, 10}, {5, 5}, {10, 5}, {10, 0}}
+	tts := []struct {
+		pg   Polygon
+		p    Point
+		want bool
+	}{
+		{square, Point{5, 5}, true},
+		{square, Point{5, 15}, false},
+		{square, Point{-5, 5}, false},
+		{fiji, Point{-18, 178}, true},
+		{fiji, Point{-18, -179}, true},
+		{fiji, Point{-18, 180}, true},
+		{fiji, Point{-18, 170}, false},
+		{fiji, Point{-18, -170}, false},
+		{fiji, Point{18, 179}, false},
+		{ell, Point{2, 8}, true},
+		{ell, Point{8, 2}, true},
+		{ell, Point{8, 8}, false},
+		{Polygon{{0, 0}, {1, 1}}, Point{0, 0}, false},
+	}
+	for i, tt := range tts {
+		if v := tt.pg.Contains(tt.p); v != tt.want {
+			t.Errorf("#%d: contains %v: wanted %v, got %v", i, tt.p, tt.want, v)
+		}
+	}
+	if b, want := fiji.Box(), (Box{-21, 176, -15, -178}); b != want {
+		t.Errorf("wanted box %+v, got %+v", want, b)
+	}
+}
@@ -0,0 +1,79 @@
+// Package geo computes distances, bearings, and areas on the earth.
+//
+// Distances and bearings treat the earth as a sphere, which is good to
+// about 0.5%; UTM and MGRS use the WGS84 ellipsoid.
+package geo
+
+import "math"
+
+// EarthRadius is the mean radius of the earth in meters.
+const EarthRadius = 6371e3
+
+// Point is a position in degrees.
+type Point struct {
+	Lat float64
+	Lon float64
+}
+
+func rad(deg float64) float64 { return deg * math.Pi / 180 }
+func deg(rad float64) float64 { return rad * 180 / math.Pi }
+
+// wrap180 wraps degrees to [-180, 180).
+func wrap180(d float64) float64 { return math.Mod(math.Mod(d+180, 360)+360, 360) - 180 }
+
+// wrap360 wraps degrees to [0, 360).
+func wrap360(d float64) float64 { return math.Mod(math.Mod(d, 360)+360, 360) }
+
+// angle is the angular distance between two points in radians.
+func angle(a, b Point) float64 {
+	lat1, lat2 := rad(a.Lat), rad(b.Lat)
+	dlat, dlon := lat2-lat1, rad(b.Lon-a.Lon)
+	h := math.Sin(dlat/2)*math.Sin(dlat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dlon/2)*math.Sin(dlon/2)
+	return 2 * math.Atan2(math.Sqrt(h), math.Sqrt(1-h))
+}
+
+// Distance is the great-circle distance between two points in meters.
+func Distance(a, b Point) float64 { return EarthRadius * angle(a, b) }
+
+// Bearing is the initial bearing from a to b in degrees from north.
+func Bearing(a, b Point) float64 {
+	lat1, lat2 := rad(a.Lat), rad(b.Lat)
+	dlon := rad(b.Lon - a.Lon)
+	y := math.Sin(dlon) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dlon)
+	return wrap360(deg(math.Atan2(y, x)))
+}
+
+// FinalBearing is the bearing on arriving at b from a in degrees from
+// north.
+func FinalBearing(a, b Point) float64 { return wrap360(Bearing(b, a) + 180) }
+
+// Destination is the point at a distance in meters along a bearing.
+func Destination(p Point, bearing, dist float64) Point {
+	d, brg := dist/EarthRadius, rad(bearing)
+	lat1, lon1 := rad(p.Lat), rad(p.Lon)
+	sinLat2 := math.Sin(lat1)*math.Cos(d) + math.Cos(lat1)*math.Sin(d)*math.Cos(brg)
+	lat2 := math.Asin(sinLat2)
+	y := math.Sin(brg) * math.Sin(d) * math.Cos(lat1)
+	x := math.Cos(d) - math.Sin(lat1)*sinLat2
+	return Point{Lat: deg(lat2), Lon: wrap180(deg(lon1 + math.Atan2(y, x)))}
+}
+
+// CrossTrack is the distance in meters from p to the great circle
+// through a and b; negative if p is left of the path from a to b.
+func CrossTrack(p, a, b Point) float64 {
+	d13, b13, b12 := angle(a, p), rad(Bearing(a, p)), rad(Bearing(a, b))
+	return EarthRadius * math.Asin(math.Sin(d13)*math.Sin(b13-b12))
+}
+
+// compassPoints name the eight principal winds.
+var compassPoints = []string{
+	"north", "northeast", "east", "southeast",
+	"south", "southwest", "west", "northwest",
+}
+
+// Compass names the nearest of the eight principal directions of a
+// bearing (e.g., "northeast").
+func Compass(bearing float64) string {
+	return compassPoints[int(math.Floor(wrap360(bearing)/45+0.5))%8]
+}
@@ -0,0 +1,117 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+var (
+	cambridge = Point{52.205, 0.119}
+	paris     = Point{48.857, 2.351}
+)
+
+func TestDistance(t *testing.T) {
+	tts := []struct {
+		a, b Point
+		want float64
+	}{
+		{cambridge, paris, 404279},
+		{Point{1, 0}, Point{2, 0}, 111194},
+		{Point{0, 1}, Point{0, 2}, 111194},
+		{Point{0, 179.5}, Point{0, -179.5}, 111194},
+		{Point{90, 0}, Point{-90, 0}, math.Pi * EarthRadius},
+		{Point{}, Point{}, 0},
+	}
+	for i, tt := range tts {
+		if v := Distance(tt.a, tt.b); math.Abs(v-tt.want) > 1 {
+			t.Errorf("#%d: wanted %g, got %g", i, tt.want, v)
+		}
+	}
+}
+
+func TestBearing(t *testing.T) {
+	tts := []struct {
+		a, b         Point
+		first, final float64
+	}{
+		{cambridge, paris, 156.1666, 157.8904},
+		{paris, cambridge, 337.8904, 336.1666},
+		{Point{0, 0}, Point{0, 1}, 90, 90},
+		{Point{0, 179.5}, Point{0, -179.5}, 90, 90},
+		{Point{0, 0}, Point{1, 0}, 0, 0},
+		{Point{0, 0}, Point{-1, 0}, 180, 180},
+	}
+	for i, tt := range tts {
+		first, final := Bearing(tt.a, tt.b), FinalBearing(tt.a, tt.b)
+		if math.Abs(first-tt.first) > 1e-4 || math.Abs(final-tt.final) > 1e-4 {
+			t.Errorf("#%d: wanted %g to %g, got %g to %g", i, tt.first, tt.final, first, final)
+		}
+	}
+}
+
+func TestDestination(t *testing.T) {
+	tts := []struct {
+		p       Point
+		bearing float64
+		dist    float64
+		want    Point
+	}{
+		{Point{51.4778, -0.0015}, 300.7, 7794, Point{51.5135, -0.0983}},
+		{Point{0, 0}, 90, 111194.9, Point{0, 1}},
+		{Point{0, 179.5}, 90, 111194.9, Point{0, -179.5}},
+		{Point{89.5, 0}, 0, 111194.9, Point{89.5, 180}},
+		{cambridge, 0, 0, cambridge},
+	}
+	for i, tt := range tts {
+		v := Destination(tt.p, tt.bearing, tt.dist)
+		if math.Abs(v.Lat-tt.want.Lat) > 1e-4 || math.Abs(wrap180(v.Lon-tt.want.Lon)) > 1e-4 {
+			t.Errorf("#%d: wanted %v, got %v", i, tt.want, v)
+		}
+	}
+}
+
+func TestCrossTrack(t *testing.T) {
+	ab := [2]Point{{53.3206, -1.7297}, {53.1887, 0.1334}}
+	equator := [2]Point{{0, 0}, {0, 10}}
+	tts := []struct {
+		p    Point
+		path [2]Point
+		want float64
+	}{
+		{Point{53.2611, -0.7972}, ab, -307.5},
+		{ab[0], ab, 0},
+		{ab[1], ab, 0},
+		{Point{1, 5}, equator, -111194.9},
+		{Point{-1, 5}, equator, 111194.9},
+		{Point{0, 180}, equator, 0},
+	}
+	for i, tt := range tts {
+		if v := CrossTrack(tt.p, tt.path[0], tt.path[1]); math.Abs(v-tt.want) > 0.1 {
+			t.Errorf("#%d: wanted %g, got %g", i, tt.want, v)
+		}
+	}
+}
+
+func TestCompass(t *testing.T) {
+	tts := []struct {
+		bearing float64
+		want    string
+	}{
+		{0, "north"},
+		{22, "north"},
+		{23, "northeast"},
+		{90, "east"},
+		{156.2, "southeast"},
+		{180, "south"},
+		{225, "southwest"},
+		{270, "west"},
+		{337.6, "north"},
+		{-45, "northwest"},
+		{720, "north"},
+	}
+	for i, tt := range tts {
+		if v := Compass(tt.bearing); v != tt.want {
+			t.Errorf("#%d: wanted %q for %g, got %q", i, tt.want, tt.bearing, v)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// WGS84 ellipsoid and UTM projection parameters.
+const (
+	wgs84A = 6378137
+	wgs84F = 1 / 298.257223563
+	utmK0  = 0.9996
+	// utmEasting and utmNorthing are the false easting of the central
+	// meridian and the false northing of the southern hemisphere.
+	utmEasting  = 500e3
+	utmNorthing = 10000e3
+)
+
+// utmBands are the 8° latitude bands from 80°S; X spans 72°N to 84°N.
+const utmBands = "CDEFGHJKLMNPQRSTUVWXX"
+
+// UTM is a position on the Universal Transverse Mercator grid.
+type UTM struct {
+	Zone int
+	// Band is the latitude band letter; bands N and above are in the
+	// northern hemisphere.
+	Band     byte
+	Easting  float64
+	Northing float64
+}
+
+// North is set if the position is in the northern hemisphere.
+func (u UTM) North() bool { return u.Band >= 'N' }
+
+// String formats the position to the meter (e.g., "31U 448252 5411933").
+func (u UTM) String() string {
+	return fmt.Sprintf("%d%c %.0f %.0f", u.Zone, u.Band, u.Easting, u.Northing)
+}
+
+// utmSeries are the Krüger series coefficients for the ellipsoid,
+// good to a few nanometers within a zone.
+var utmSeries = func() (s struct {
+	e, a        float64
+	alpha, beta [7]float64
+}) {
+	n := wgs84F / (2 - wgs84F)
+	n2, n3, n4, n5, n6 := n*n, n*n*n, n*n*n*n, n*n*n*n*n, n*n*n*n*n*n
+	s.e = math.Sqrt(wgs84F * (2 - wgs84F))
+	s.a = wgs84A / (1 + n) * (1 + n2/4 + n4/64 + n6/256)
+	s.alpha = [7]float64{0,
+		n/2 - 2*n2/3 + 5*n3/16 + 41*n4/180 - 127*n5/288 + 7891*n6/37800,
+		13*n2/48 - 3*n3/5 + 557*n4/1440 + 281*n5/630 - 1983433*n6/1935360,
+		61*n3/240 - 103*n4/140 + 15061*n5/26880 + 167603*n6/181440,
+		49561*n4/161280 - 179*n5/168 + 6601661*n6/7257600,
+		34729*n5/80640 - 3418889*n6/1995840,
+		212378941 * n6 / 319334400,
+	}
+	s.beta = [7]float64{0,
+		n/2 - 2*n2/3 + 37*n3/96 - n4/360 - 81*n5/512 + 96199*n6/604800,
+		n2/48 + n3/15 - 437*n4/1440 + 46*n5/105 - 1118711*n6/3870720,
+		17*n3/480 - 37*n4/840 - 209*n5/4480 + 5569*n6/90720,
+		4397*n4/161280 - 11*n5/504 - 830251*n6/7257600,
+		4583*n5/161280 - 108847*n6/3991680,
+		20648693 * n6 / 638668800,
+	}
+	return s
+}()
+
+// utmZone is the zone of a point, with the exceptions around Norway
+// and Svalbard.
+func utmZone(lon float64, band byte) int {
+	zone := int(math.Floor((lon+180)/6)) + 1
+	if zone > 60 {
+		zone = 1
+	}
+	switch {
+	case band == 'V' && zone == 31 && lon >= 3:
+		zone = 32
+	case band == 'X' && zone == 32:
+		if zone = 31; lon >= 9 {
+			zone = 33
+		}
+	case band == 'X' && zone == 34:
+		if zone = 33; lon >= 21 {
+			zone = 35
+		}
+	case band == 'X' && zone == 36:
+		if zone = 35; lon >= 33 {
+			zone = 37
+		}
+	}
+	return zone
+}
+
+// ToUTM projects a point onto the UTM grid. UTM covers 80°S to 84°N.
+func ToUTM(p Point) (UTM, error) {
+	if p.Lat < -80 || p.Lat > 84 || math.IsNaN(p.Lat) || math.IsNaN(p.Lon) {
+		return UTM{}, fmt.Errorf("geo: %g,%g is outside utm", p.Lat, p.Lon)
+	}
+	lon := wrap180(p.Lon)
+	band := utmBands[int(math.Floor(p.Lat/8+10))]
+	zone := utmZone(lon, band)
+	return toUTM(p.Lat, lon, zone, band), nil
+}
+
+func toUTM(lat, lon float64, zone int, band byte) UTM {
+	s := &utmSeries
+	phi := rad(lat)
+	lam := rad(lon - float64((zone-1)*6-180+3))
+
+	tau := math.Tan(phi)
+	sigma := math.Sinh(s.e * math.Atanh(s.e*tau/math.Sqrt(1+tau*tau)))
+	tauc := tau*math.Sqrt(1+sigma*sigma) - sigma*math.Sqrt(1+tau*tau)
+	xic := math.Atan2(tauc, math.Cos(lam))
+	etac := math.Asinh(math.Sin(lam) / math.Sqrt(tauc*tauc+math.Cos(lam)*math.Cos(lam)))
+
+	xi, eta := xic, etac
+	for j := 1; j <= 6; j++ {
+		k := 2 * float64(j)
+		xi += s.alpha[j] * math.Sin(k*xic) * math.Cosh(k*etac)
+		eta += s.alpha[j] * math.Cos(k*xic) * math.Sinh(k*etac)
+	}
+	u := UTM{
+		Zone:     zone,
+		Band:     band,
+		Easting:  utmK0*s.a*eta + utmEasting,
+		Northing: utmK0 * s.a * xi,
+	}
+	if !u.North() {
+		u.Northing += utmNorthing
+	}
+	return u
+}
+
+// Point is the position of a grid reference.
+func (u UTM) Point() Point {
+	s := &utmSeries
+	y := u.Northing
+	if !u.North() {
+		y -= utmNorthing
+	}
+	xi := y / (utmK0 * s.a)
+	eta := (u.Easting - utmEasting) / (utmK0 * s.a)
+
+	xic, etac := xi, eta
+	for j := 1; j <= 6; j++ {
+		k := 2 * float64(j)
+		xic -= s.beta[j] * math.Sin(k*xi) * math.Cosh(k*eta)
+		etac -= s.beta[j] * math.Cos(k*xi) * math.Sinh(k*eta)
+	}
+	tauc := math.Sin(xic) / math.Sqrt(math.Sinh(etac)*math.Sinh(etac)+math.Cos(xic)*math.Cos(xic))
+
+	// Solve for the conformal latitude by Newton-Raphson.
+	e2 := s.e * s.e
+	tau := tauc
+	for i := 0; i < 10; i++ {
+		sigma := math.Sinh(s.e * math.Atanh(s.e*tau/math.Sqrt(1+tau*tau)))
+		taui := tau*math.Sqrt(1+sigma*sigma) - sigma*math.Sqrt(1+tau*tau)
+		delta := (tauc - taui) / math.Sqrt(1+taui*taui) * (1 + (1-e2)*tau*tau) / ((1 - e2) * math.Sqrt(1+tau*tau))
+		if tau += delta; math.Abs(delta) < 1e-12 {
+			break
+		}
+	}
+	lam0 := float64((u.Zone-1)*6 - 180 + 3)
+	return Point{
+		Lat: deg(math.Atan(tau)),
+		Lon: wrap180(lam0 + deg(math.Atan2(math.Sinh(etac), math.Cos(xic)))),
+	}
+}
+
+// mgrsColumns and mgrsRows letter the 100km squares of a zone; zones
+// cycle through the column sets and alternate the row sets.
+var (
+	mgrsColumns = [3]string{"ABCDEFGH", "JKLMNPQR", "STUVWXYZ"}
+	mgrsRows    = [2]string{"ABCDEFGHJKLMNPQRSTUV", "FGHJKLMNPQRSTUVABCDE"}
+)
+
+// MGRS is a position on the Military Grid Reference System.
+type MGRS struct {
+	Zone int
+	Band byte
+	// Square is the column and row letter of the 100km square.
+	Square string
+	// Easting and Northing are in meters within the square.
+	Easting  float64
+	Northing float64
+}
+
+// MGRS is the military grid reference of the position.
+func (u UTM) MGRS() MGRS {
+	col := int(math.Floor(u.Easting / 100e3))
+	row := int(math.Floor(u.Northing/100e3)) % 20
+	return MGRS{
+		Zone: u.Zone,
+		Band: u.Band,
+		Square: string([]byte{
+			mgrsColumns[(u.Zone-1)%3][col-1],
+			mgrsRows[(u.Zone-1)%2][row],
+		}),
+		Easting:  math.Mod(u.Easting, 100e3),
+		Northing: math.Mod(u.Northing, 100e3),
+	}
+}
+
+// String formats the reference to the meter (e.g., "31U DQ 48251
+// 11932").
+func (m MGRS) String() string {
+	return fmt.Sprintf("%d%c %s %05d %05d", m.Zone, m.Band, m.Square,
+		int(math.Floor(m.Easting)), int(math.Floor(m.Northing)))
+}
+
+// UTM is the UTM position of the reference.
+func (m MGRS) UTM() (UTM, error) {
+	if m.Zone < 1 || m.Zone > 60 || strings.IndexByte(utmBands, m.Band) < 0 || len(m.Square) != 2 {
+		return UTM{}, fmt.Errorf("geo: bad mgrs reference %v", m)
+	}
+	col := strings.IndexByte(mgrsColumns[(m.Zone-1)%3], m.Square[0])
+	row := strings.IndexByte(mgrsRows[(m.Zone-1)%2], m.Square[1])
+	if col < 0 || row < 0 {
+		return UTM{}, fmt.Errorf("geo: bad mgrs square %q in zone %d", m.Square, m.Zone)
+	}
+	u := UTM{
+		Zone:     m.Zone,
+		Band:     m.Band,
+		Easting:  float64(col+1)*100e3 + m.Easting,
+		Northing: float64(row)*100e3 + m.Northing,
+	}
+	// Rows repeat every 2000km; pick the repeat in the latitude band.
+	lat := float64(strings.IndexByte(utmBands, m.Band)-10) * 8
+	bottom := math.Floor(toUTM(lat, 3, 31, m.Band).Northing/100e3) * 100e3
+	for u.Northing < bottom {
+		u.Northing += 2000e3
+	}
+	return u, nil
+}
+
+// ParseMGRS parses a reference with or without spaces and with one to
+// five digits of easting and northing (e.g., "31U DQ 48251 11932",
+// "31UDQ4811").
+func ParseMGRS(s string) (MGRS, error) {
+	bad := errors.New("geo: bad mgrs reference " + strconv.Quote(s))
+	s = strings.ToUpper(strings.Join(strings.Fields(s), ""))
+	i := 0
+	for i < len(s) && i < 2 && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	if i == 0 || len(s) < i+3 {
+		return MGRS{}, bad
+	}
+	zone, _ := strconv.Atoi(s[:i])
+	m := MGRS{Zone: zone, Band: s[i], Square: s[i+1 : i+3]}
+	digits := s[i+3:]
+	if len(digits)%2 != 0 || len(digits) > 10 {
+		return MGRS{}, bad
+	}
+	if n := len(digits) / 2; n > 0 {
+		e, err1 := strconv.Atoi(digits[:n])
+		north, err2 := strconv.Atoi(digits[n:])
+		if err1 != nil || err2 != nil {
+			return MGRS{}, bad
+		}
+		scale := math.Pow(10, float64(5-n))
+		m.Easting, m.Northing = float64(e)*scale, float64(north)*scale
+	}
+	if _, err := m.UTM(); err != nil {
+		return MGRS{}, bad
+	}
+	return m, nil
+}
@@ -0,0 +1,75 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestToUTM(t *testing.T) {
+	tts := []struct {
+		p    Point
+		want UTM
+		mgrs string
+	}{
+		{Point{0, 0}, UTM{31, 'N', 166021.44, 0}, "31N AA 66021 00000"},
+		// The CN Tower, given as 17T 630084 4833438.
+		{Point{43.64256667, -79.38713889}, UTM{17, 'T', 630084.31, 4833438.55}, "17T PJ 30084 33438"},
+		{Point{-33.8568, 151.2153}, UTM{56, 'H', 334900.57, 6252288.75}, "56H LH 34900 52288"},
+		// Norway and Svalbard take wider zones.
+		{Point{60.5, 4}, UTM{32, 'V', 225510.35, 6717531.16}, "32V KN 25510 17531"},
+		{Point{78, 15}, UTM{33, 'X', 500000, 8658369.59}, "33X WG 00000 58369"},
+		{Point{-79.9, -179.9}, UTM{1, 'C', 443247.87, 1128161.37}, "1C DM 43247 28161"},
+	}
+	for i, tt := range tts {
+		u, err := ToUTM(tt.p)
+		if err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		if u.Zone != tt.want.Zone || u.Band != tt.want.Band ||
+			math.Abs(u.Easting-tt.want.Easting) > 0.01 || math.Abs(u.Northing-tt.want.Northing) > 0.01 {
+			t.Errorf("#%d: wanted %v, got %v", i, tt.want, u)
+		}
+		if s := u.MGRS().String(); s != tt.mgrs {
+			t.Errorf("#%d: wanted %q, got %q", i, tt.mgrs, s)
+		}
+		p := u.Point()
+		if math.Abs(p.Lat-tt.p.Lat) > 1e-9 || math.Abs(p.Lon-tt.p.Lon) > 1e-9 {
+			t.Errorf("#%d: wanted %v back, got %v", i, tt.p, p)
+		}
+	}
+	for _, p := range []Point{{-80.1, 0}, {84.1, 0}, {math.NaN(), 0}} {
+		if _, err := ToUTM(p); err == nil {
+			t.Errorf("expected %v outside utm", p)
+		}
+	}
+}
+
+func TestParseMGRS(t *testing.T) {
+	tts := []struct {
+		s    string
+		want UTM
+	}{
+		// The Eiffel Tower.
+		{"31U DQ 48251 11932", UTM{31, 'U', 448251, 5411932}},
+		{"31udq4825111932", UTM{31, 'U', 448251, 5411932}},
+		{"31U DQ 4811", UTM{31, 'U', 448000, 5411000}},
+		{"31U DQ", UTM{31, 'U', 400000, 5400000}},
+		{"17T PJ 30084 33438", UTM{17, 'T', 630084, 4833438}},
+		{"56H LH 34900 52288", UTM{56, 'H', 334900, 6252288}},
+		{"1C DM 43247 28161", UTM{1, 'C', 443247, 1128161}},
+	}
+	for i, tt := range tts {
+		m, err := ParseMGRS(tt.s)
+		if err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		if u, err := m.UTM(); err != nil || u != tt.want {
+			t.Errorf("#%d: wanted %v, got %v (%v)", i, tt.want, u, err)
+		}
+	}
+	for _, s := range []string{"", "31U", "31U D", "31U DQ 123", "31U IQ 1 1", "61U DQ 1 1", "31U DQ 1x 11"} {
+		if _, err := ParseMGRS(s); err == nil {
+			t.Errorf("expected %q to fail", s)
+		}
+	}
+}
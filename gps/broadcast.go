@@ -0,0 +1,138 @@
+package gps
+
+import (
+	"context"
+	"sync"
+)
+
+// Backpressure decides what a subscription does when its reader falls
+// behind.
+type Backpressure int
+
+const (
+	// Block stalls the stream until the reader catches up.
+	Block Backpressure = iota
+	// DropOldest discards the oldest queued message.
+	DropOldest
+	// LatestOnly keeps only the newest message.
+	LatestOnly
+)
+
+// subscriberQueue is the number of messages queued for a subscription.
+const subscriberQueue = 16
+
+// Broadcaster fans out a GPS stream to independent subscriptions.
+type Broadcaster struct {
+	mu    sync.Mutex
+	subs  map[*subscription]struct{}
+	donec chan struct{}
+}
+
+type subscription struct {
+	ctx context.Context
+	bp  Backpressure
+	in  chan NMEA
+	out chan NMEA
+}
+
+// NewBroadcaster reads a GPS stream and publishes it to subscribers.
+func NewBroadcaster(g *GPS) *Broadcaster { return NewChanBroadcaster(g.NMEA()) }
+
+// NewChanBroadcaster publishes a stream of messages, such as a
+// Selector's, to subscribers.
+func NewChanBroadcaster(ch <-chan NMEA) *Broadcaster {
+	b := &Broadcaster{
+		subs:  make(map[*subscription]struct{}),
+		donec: make(chan struct{}),
+	}
+	go b.run(ch)
+	return b
+}
+
+func (b *Broadcaster) run(ch <-chan NMEA) {
+	for msg := range ch {
+		b.mu.Lock()
+		subs := make([]*subscription, 0, len(b.subs))
+		for s := range b.subs {
+			subs = append(subs, s)
+		}
+		b.mu.Unlock()
+		for _, s := range subs {
+			select {
+			case s.in <- msg:
+			case <-s.ctx.Done():
+			}
+		}
+	}
+	b.mu.Lock()
+	close(b.donec)
+	for s := range b.subs {
+		close(s.in)
+	}
+	b.subs = nil
+	b.mu.Unlock()
+}
+
+// Done is closed when the GPS stream ends.
+func (b *Broadcaster) Done() <-chan struct{} { return b.donec }
+
+// Subscribe streams messages until the context is done or the GPS
+// stream ends.
+func (b *Broadcaster) Subscribe(ctx context.Context, bp Backpressure) <-chan NMEA {
+	s := &subscription{
+		ctx: ctx,
+		bp:  bp,
+		in:  make(chan NMEA),
+		out: make(chan NMEA),
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subs == nil {
+		close(s.out)
+		return s.out
+	}
+	b.subs[s] = struct{}{}
+	go func() {
+		s.run()
+		b.mu.Lock()
+		delete(b.subs, s)
+		b.mu.Unlock()
+	}()
+	return s.out
+}
+
+// run queues messages from the broadcaster for the reader.
+func (s *subscription) run() {
+	defer close(s.out)
+	n := subscriberQueue
+	if s.bp == LatestOnly {
+		n = 1
+	}
+	var q []NMEA
+	in := s.in
+	for in != nil || len(q) > 0 {
+		var out chan NMEA
+		var next NMEA
+		if len(q) > 0 {
+			out, next = s.out, q[0]
+		}
+		inc := in
+		if s.bp == Block && len(q) >= n {
+			inc = nil
+		}
+		select {
+		case msg, ok := <-inc:
+			if !ok {
+				in = nil
+				continue
+			}
+			if q = append(q, msg); len(q) > n {
+				q = q[1:]
+			}
+		case out <- next:
+			q = q[1:]
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package gps
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func rmcLine(i int) string {
+	l := nmeaSentence(fmt.Sprintf("GPRMC,04%04d.00,V,,,,,,,120318,,,N", i))
+	return strings.TrimSuffix(string(l), "\r\n") + "\n"
+}
+
+func readLines(ch <-chan NMEA) (ret []string) {
+	for msg := range ch {
+		ret = append(ret, msg.Line())
+	}
+	return ret
+}
+
+func TestBroadcaster(t *testing.T) {
+	pr, pw := io.Pipe()
+	b := NewBroadcaster(newGPS(pr, Config{}))
+	ctx := context.Background()
+	blocks := []<-chan NMEA{b.Subscribe(ctx, Block), b.Subscribe(ctx, Block)}
+	oldest := b.Subscribe(ctx, DropOldest)
+	latest := b.Subscribe(ctx, LatestOnly)
+	cctx, cancel := context.WithCancel(ctx)
+	canceled := b.Subscribe(cctx, Block)
+	cancel()
+	if _, ok := <-canceled; ok {
+		t.Errorf("expected canceled subscription to close")
+	}
+
+	const n = 40
+	resc := make(chan []string, len(blocks))
+	for _, ch := range blocks {
+		go func(ch <-chan NMEA) { resc <- readLines(ch) }(ch)
+	}
+	for i := 0; i < n; i++ {
+		io.WriteString(pw, rmcLine(i))
+	}
+	pw.Close()
+	<-b.Done()
+	for range blocks {
+		lines := <-resc
+		if len(lines) != n {
+			t.Fatalf("expected %d lines, got %d", n, len(lines))
+		}
+		for i, l := range lines {
+			if l != rmcLine(i) {
+				t.Errorf("#%d: wanted %q, got %q", i, rmcLine(i), l)
+			}
+		}
+	}
+	lines := readLines(oldest)
+	if len(lines) != subscriberQueue || lines[0] != rmcLine(n-subscriberQueue) {
+		t.Errorf("expected newest %d lines, got %q", subscriberQueue, lines)
+	}
+	lines = readLines(latest)
+	if len(lines) != 1 || lines[0] != rmcLine(n-1) {
+		t.Errorf("expected latest line, got %q", lines)
+	}
+	if _, ok := <-b.Subscribe(ctx, Block); ok {
+		t.Errorf("expected closed subscription after stream ends")
+	}
+}
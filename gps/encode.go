@@ -0,0 +1,170 @@
+package gps
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Fix is a position fix to encode as NMEA. Unknown values are NaN.
+type Fix struct {
+	Time time.Time
+	// Valid is unset while the receiver has no fix; only the time is
+	// encoded.
+	Valid     bool
+	Latitude  float64
+	Longitude float64
+	// Altitude is the height above mean sea level in meters.
+	Altitude float64
+	// Speed is the ground speed in m/s.
+	Speed float64
+	// Course is the true course over ground in degrees.
+	Course float64
+	// MagVar is the magnetic variation in degrees; east is positive.
+	MagVar float64
+	// Satellites is the number of satellites in use.
+	Satellites int
+	HDOP       float64
+	// Quality is the GGA fix quality; valid fixes default to FixGPS.
+	Quality FixQuality
+}
+
+// NewFix collects the fix data of a message. Data the message does not
+// carry is unknown.
+func NewFix(msg NMEAi) Fix {
+	if n, ok := msg.(NMEA); ok {
+		msg = n.Msg()
+	}
+	f := Fix{
+		Time:      msg.Fix(),
+		Valid:     msg.Valid(),
+		Latitude:  msg.Latitude(),
+		Longitude: msg.Longitude(),
+		Altitude:  math.NaN(),
+		Speed:     msg.Speed(),
+		Course:    msg.Course(),
+		MagVar:    msg.MagVar(),
+		HDOP:      math.NaN(),
+	}
+	if a, ok := msg.(interface{ Altitude() float64 }); ok {
+		f.Altitude = a.Altitude()
+	}
+	if s, ok := msg.(interface{ Satellites() int }); ok {
+		f.Satellites = s.Satellites()
+	}
+	if h, ok := msg.(interface{ HDOP() float64 }); ok {
+		f.HDOP = h.HDOP()
+	}
+	if g, ok := msg.(*GGA); ok {
+		f.Quality = g.Quality()
+	}
+	return f
+}
+
+// Encoder formats fixes as NMEA sentences.
+type Encoder struct {
+	// Talker is the talker id; defaults to "GP".
+	Talker string
+}
+
+func (e Encoder) sentence(typ, fields string) []byte {
+	talker := e.Talker
+	if talker == "" {
+		talker = "GP"
+	}
+	return nmeaSentence(talker + typ + "," + fields)
+}
+
+// RMC encodes the recommended minimum sentence of a fix.
+func (e Encoder) RMC(f Fix) []byte {
+	if !f.Valid {
+		return e.sentence("RMC", fmt.Sprintf("%s,V,,,,,,,%s,,,N",
+			nmeaTime(f.Time), nmeaDate(f.Time)))
+	}
+	lat, ns := nmeaDegrees(f.Latitude, 2, "N", "S")
+	lon, we := nmeaDegrees(f.Longitude, 3, "E", "W")
+	mv, mvwe := "", ""
+	if !math.IsNaN(f.MagVar) {
+		mv, mvwe = nmeaFloat(math.Abs(f.MagVar), 1), "E"
+		if f.MagVar < 0 {
+			mvwe = "W"
+		}
+	}
+	return e.sentence("RMC", fmt.Sprintf("%s,A,%s,%s,%s,%s,%s,%s,%s,%s,%s,A",
+		nmeaTime(f.Time), lat, ns, lon, we, nmeaKnots(f.Speed),
+		nmeaCourse(f.Course), nmeaDate(f.Time), mv, mvwe))
+}
+
+// GGA encodes the fix data sentence of a fix.
+func (e Encoder) GGA(f Fix) []byte {
+	if !f.Valid {
+		return e.sentence("GGA", fmt.Sprintf("%s,,,,,0,00,,,M,,M,,", nmeaTime(f.Time)))
+	}
+	lat, ns := nmeaDegrees(f.Latitude, 2, "N", "S")
+	lon, we := nmeaDegrees(f.Longitude, 3, "E", "W")
+	q := f.Quality
+	if q == FixInvalid {
+		q = FixGPS
+	}
+	return e.sentence("GGA", fmt.Sprintf("%s,%s,%s,%s,%s,%d,%02d,%s,%s,M,,M,,",
+		nmeaTime(f.Time), lat, ns, lon, we, q, f.Satellites,
+		nmeaFloat(f.HDOP, 1), nmeaFloat(f.Altitude, 1)))
+}
+
+// VTG encodes the course and speed over ground of a fix.
+func (e Encoder) VTG(f Fix) []byte {
+	if !f.Valid {
+		return e.sentence("VTG", ",T,,M,,N,,K,N")
+	}
+	magtrack := ""
+	if !math.IsNaN(f.Course) && !math.IsNaN(f.MagVar) {
+		magtrack = nmeaCourse(f.Course - f.MagVar)
+	}
+	return e.sentence("VTG", fmt.Sprintf("%s,T,%s,M,%s,N,%s,K,A",
+		nmeaCourse(f.Course), magtrack, nmeaKnots(f.Speed),
+		nmeaFloat(f.Speed*3.6, 2)))
+}
+
+// nmeaTime formats the time of a fix as hhmmss.ss.
+func nmeaTime(t time.Time) string {
+	t = t.UTC()
+	return fmt.Sprintf("%02d%02d%02d.%02d", t.Hour(), t.Minute(), t.Second(), t.Nanosecond()/1e7)
+}
+
+// nmeaDate formats the date of a fix as ddmmyy.
+func nmeaDate(t time.Time) string {
+	t = t.UTC()
+	return fmt.Sprintf("%02d%02d%02d", t.Day(), t.Month(), t.Year()%100)
+}
+
+// nmeaDegrees formats degrees as DDMM.MMMM with the given degree digits.
+func nmeaDegrees(v float64, digits int, pos, neg string) (string, string) {
+	hemi := pos
+	if v < 0 {
+		v, hemi = -v, neg
+	}
+	m := int64(math.Round(v * 60 * 1e4))
+	deg, min := m/(60*1e4), m%(60*1e4)
+	return fmt.Sprintf("%0*d%02d.%04d", digits, deg, min/1e4, min%1e4), hemi
+}
+
+// nmeaFloat formats an optional field; empty if NaN.
+func nmeaFloat(v float64, prec int) string {
+	if math.IsNaN(v) {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', prec, 64)
+}
+
+// nmeaKnots formats a speed in m/s as knots.
+func nmeaKnots(v float64) string { return nmeaFloat(v*3600/1852, 2) }
+
+// nmeaCourse formats a course in degrees within [0, 360).
+func nmeaCourse(v float64) string {
+	v = math.Mod(math.Mod(v, 360)+360, 360)
+	if math.Round(v*10) == 3600 {
+		v = 0
+	}
+	return nmeaFloat(v, 1)
+}
@@ -0,0 +1,122 @@
+package gps
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEncodeRMC(t *testing.T) {
+	f := Fix{
+		Time:      time.Date(2018, 3, 23, 12, 35, 19, 0, time.UTC),
+		Valid:     true,
+		Latitude:  48.1173,
+		Longitude: 11.516666666666667,
+		Speed:     Knots(22.4).MetersPerSecond(),
+		Course:    84.4,
+		MagVar:    -3.1,
+	}
+	want := "$GPRMC,123519.00,A,4807.0380,N,01131.0000,E,22.40,84.4,230318,3.1,W,A*1D\r\n"
+	if got := string(Encoder{}.RMC(f)); got != want {
+		t.Errorf("wanted %q, got %q", want, got)
+	}
+}
+
+func TestVTG(t *testing.T) {
+	g := newGPS(&rc{bytes.NewReader([]byte("$GPVTG,054.7,T,034.4,M,005.5,N,010.2,K,A*25\n"))}, Config{})
+	msg := <-g.NMEA()
+	vtg, ok := msg.Msg().(*VTG)
+	if !ok {
+		t.Fatalf("expected VTG, got %T", msg.Msg())
+	}
+	if vtg.Course() != 54.7 || vtg.MagneticCourse() != 34.4 || vtg.Knots() != 5.5 || vtg.Mode() != "A" {
+		t.Errorf("bad VTG %+v", vtg)
+	}
+	if vtg.Valid() {
+		t.Errorf("expected VTG without a fix")
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	tts := []Fix{
+		{
+			Time:       time.Date(2018, 3, 12, 2, 55, 3, 250e6, time.UTC),
+			Valid:      true,
+			Latitude:   48.1173,
+			Longitude:  11.5167,
+			Altitude:   545.4,
+			Speed:      5.5,
+			Course:     84.4,
+			MagVar:     3.1,
+			Satellites: 8,
+			HDOP:       0.9,
+			Quality:    FixGPS,
+		},
+		{
+			Time:       time.Date(2019, 12, 31, 23, 59, 59, 990e6, time.UTC),
+			Valid:      true,
+			Latitude:   -33.856785,
+			Longitude:  -151.215257,
+			Altitude:   -12.5,
+			Speed:      0,
+			Course:     359.96,
+			MagVar:     math.NaN(),
+			Satellites: 12,
+			HDOP:       math.NaN(),
+			Quality:    FixDGPS,
+		},
+		{Time: time.Date(2018, 3, 12, 4, 30, 19, 0, time.UTC)},
+	}
+	for i, tt := range tts {
+		var b []byte
+		b = append(b, Encoder{}.RMC(tt)...)
+		b = append(b, Encoder{Talker: "GN"}.GGA(tt)...)
+		b = append(b, Encoder{}.VTG(tt)...)
+		g := newGPS(&rc{bytes.NewReader(b)}, Config{})
+		var msgs []NMEA
+		for msg := range g.NMEA() {
+			msgs = append(msgs, msg)
+		}
+		if st := g.Stats(); len(msgs) != 3 || st.BadChecksum != 0 || st.Unparsable != 0 {
+			t.Fatalf("#%d: bad sentences %q: %+v", i, b, st)
+		}
+		rmc, gga, vtg := NewFix(msgs[0]), NewFix(msgs[1]), msgs[2].Msg().(*VTG)
+		if !rmc.Time.Equal(tt.Time) || rmc.Valid != tt.Valid || gga.Valid != tt.Valid {
+			t.Errorf("#%d: wanted %v (valid %v), got %v (valid %v, %v)",
+				i, tt.Time, tt.Valid, rmc.Time, rmc.Valid, gga.Valid)
+		}
+		if msgs[1].Talker() != "GN" {
+			t.Errorf("#%d: expected GN talker, got %q", i, msgs[1].Talker())
+		}
+		if !tt.Valid {
+			if !math.IsNaN(rmc.Latitude) || gga.Quality != FixInvalid || vtg.Mode() != "N" {
+				t.Errorf("#%d: expected void fix, got %+v %+v", i, rmc, gga)
+			}
+			continue
+		}
+		for _, f := range []Fix{rmc, gga} {
+			// DDMM.MMMM is good to about 0.2m.
+			if math.Abs(f.Latitude-tt.Latitude) > 1e-6 || math.Abs(f.Longitude-tt.Longitude) > 1e-6 {
+				t.Errorf("#%d: wanted %g,%g, got %g,%g", i, tt.Latitude, tt.Longitude, f.Latitude, f.Longitude)
+			}
+		}
+		if gga.Altitude != tt.Altitude || gga.Satellites != tt.Satellites || gga.Quality != tt.Quality {
+			t.Errorf("#%d: wanted %+v, got %+v", i, tt, gga)
+		}
+		if math.IsNaN(tt.HDOP) != math.IsNaN(gga.HDOP) || math.Abs(gga.HDOP-tt.HDOP) > 0.05 {
+			t.Errorf("#%d: wanted hdop %g, got %g", i, tt.HDOP, gga.HDOP)
+		}
+		if math.IsNaN(tt.MagVar) != math.IsNaN(rmc.MagVar) || math.Abs(rmc.MagVar-tt.MagVar) > 0.05 {
+			t.Errorf("#%d: wanted magvar %g, got %g", i, tt.MagVar, rmc.MagVar)
+		}
+		for _, f := range []NMEAi{msgs[0], vtg} {
+			if math.Abs(f.Speed()-tt.Speed) > 0.01 {
+				t.Errorf("#%d: wanted speed %g, got %g", i, tt.Speed, f.Speed())
+			}
+			if d := math.Mod(math.Abs(f.Course()-tt.Course), 360); math.Min(d, 360-d) > 0.05 {
+				t.Errorf("#%d: wanted course %g, got %g", i, tt.Course, f.Course())
+			}
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package gps
+
+import (
+	"math"
+	"time"
+
+	"github.com/bikeos/bosd/geo"
+)
+
+// FilterConfig tunes a position filter. Zero values take defaults
+// suited to a bike.
+type FilterConfig struct {
+	// MaxSpeed rejects fixes implying faster travel, in m/s; defaults
+	// to 25 (90 km/h, a fast descent).
+	MaxSpeed float64
+	// MaxRejects restarts the filter at a fix after rejecting that many
+	// in a row, in case the rejected fixes were right; defaults to 5.
+	MaxRejects int
+	// Noise is the standard deviation of fix positions in meters;
+	// defaults to 5.
+	Noise float64
+	// SpeedNoise is the standard deviation of fix speeds in m/s;
+	// defaults to 0.5.
+	SpeedNoise float64
+	// Accel is the standard deviation of acceleration in m/s²;
+	// defaults to 2.
+	Accel float64
+}
+
+// Filter rejects impossible jumps between fixes and smooths their
+// position and velocity with a Kalman filter.
+type Filter struct {
+	cfg FilterConfig
+	// lat0 and lon0 are the origin of the local frame in degrees.
+	lat0, lon0 float64
+	// e and n track east and north in meters from the origin.
+	e, n kalman1D
+	// last is the time and local position of the last accepted fix.
+	last         time.Time
+	lastE, lastN float64
+	rejects      int
+}
+
+func NewFilter(cfg FilterConfig) *Filter {
+	if cfg.MaxSpeed == 0 {
+		cfg.MaxSpeed = 25
+	}
+	if cfg.MaxRejects == 0 {
+		cfg.MaxRejects = 5
+	}
+	if cfg.Noise == 0 {
+		cfg.Noise = 5
+	}
+	if cfg.SpeedNoise == 0 {
+		cfg.SpeedNoise = 0.5
+	}
+	if cfg.Accel == 0 {
+		cfg.Accel = 2
+	}
+	return &Filter{cfg: cfg}
+}
+
+// Update filters the fix of a message. Returns false if the message has
+// no fix, repeats the last fix time, or is rejected as a jump.
+func (f *Filter) Update(msg NMEAi) (Fix, bool) {
+	fix := NewFix(msg)
+	if !fix.Valid || fix.Time.IsZero() || math.IsNaN(fix.Latitude) || math.IsNaN(fix.Longitude) {
+		return Fix{}, false
+	}
+	if f.last.IsZero() {
+		f.reset(fix)
+		return f.estimate(fix), true
+	}
+	dt := fix.Time.Sub(f.last).Seconds()
+	if dt <= 0 {
+		return Fix{}, false
+	}
+	e, n := f.local(fix.Latitude, fix.Longitude)
+	jump := math.Hypot(e-f.lastE, n-f.lastN) / dt
+	if jump > f.cfg.MaxSpeed || fix.Speed > f.cfg.MaxSpeed {
+		if f.rejects++; f.rejects < f.cfg.MaxRejects {
+			return Fix{}, false
+		}
+		f.reset(fix)
+		return f.estimate(fix), true
+	}
+	f.rejects = 0
+	f.last, f.lastE, f.lastN = fix.Time, e, n
+	ve, vn := fixVelocity(fix)
+	pv, vv := f.cfg.Noise*f.cfg.Noise, f.cfg.SpeedNoise*f.cfg.SpeedNoise
+	f.e.predict(dt, f.cfg.Accel)
+	f.n.predict(dt, f.cfg.Accel)
+	f.e.update(e, pv, ve, vv)
+	f.n.update(n, pv, vn, vv)
+	return f.estimate(fix), true
+}
+
+// reset restarts the filter at a fix.
+func (f *Filter) reset(fix Fix) {
+	f.lat0, f.lon0 = fix.Latitude, fix.Longitude
+	f.last, f.lastE, f.lastN, f.rejects = fix.Time, 0, 0, 0
+	ve, vn := fixVelocity(fix)
+	if math.IsNaN(ve) {
+		ve, vn = 0, 0
+	}
+	// Start unsure of the velocity so the first fixes settle it.
+	pv, vv := f.cfg.Noise*f.cfg.Noise, f.cfg.MaxSpeed*f.cfg.MaxSpeed
+	f.e = kalman1D{x: [2]float64{0, ve}, p: [2][2]float64{{pv, 0}, {0, vv}}}
+	f.n = kalman1D{x: [2]float64{0, vn}, p: [2][2]float64{{pv, 0}, {0, vv}}}
+}
+
+// estimate replaces the motion of a fix with the filter state.
+func (f *Filter) estimate(fix Fix) Fix {
+	fix.Latitude = f.lat0 + f.n.x[0]/geo.EarthRadius*180/math.Pi
+	fix.Longitude = f.lon0 + f.e.x[0]/(geo.EarthRadius*math.Cos(f.lat0*math.Pi/180))*180/math.Pi
+	fix.Speed = math.Hypot(f.e.x[1], f.n.x[1])
+	fix.Course = math.Mod(math.Atan2(f.e.x[1], f.n.x[1])*180/math.Pi+360, 360)
+	return fix
+}
+
+// local projects a position to meters east and north of the origin.
+func (f *Filter) local(lat, lon float64) (e, n float64) {
+	e = (lon - f.lon0) * math.Pi / 180 * geo.EarthRadius * math.Cos(f.lat0*math.Pi/180)
+	n = (lat - f.lat0) * math.Pi / 180 * geo.EarthRadius
+	return e, n
+}
+
+// fixVelocity is the east and north velocity of a fix; NaN if the fix
+// has no speed and course.
+func fixVelocity(fix Fix) (ve, vn float64) {
+	if math.IsNaN(fix.Speed) || math.IsNaN(fix.Course) {
+		return math.NaN(), math.NaN()
+	}
+	c := fix.Course * math.Pi / 180
+	return fix.Speed * math.Sin(c), fix.Speed * math.Cos(c)
+}
+
+// kalman1D tracks position and velocity along one axis with a constant
+// velocity model.
+type kalman1D struct {
+	x [2]float64
+	p [2][2]float64
+}
+
+// predict advances the state by dt seconds under random acceleration.
+func (k *kalman1D) predict(dt, accel float64) {
+	k.x[0] += dt * k.x[1]
+	p := k.p
+	k.p[0][0] = p[0][0] + dt*(p[0][1]+p[1][0]) + dt*dt*p[1][1]
+	k.p[0][1] = p[0][1] + dt*p[1][1]
+	k.p[1][0] = p[1][0] + dt*p[1][1]
+	q := accel * accel
+	k.p[0][0] += q * dt * dt * dt * dt / 4
+	k.p[0][1] += q * dt * dt * dt / 2
+	k.p[1][0] += q * dt * dt * dt / 2
+	k.p[1][1] += q * dt * dt
+}
+
+// update corrects the state with a measured position and, unless NaN,
+// velocity, given their variances.
+func (k *kalman1D) update(pos, posVar, vel, velVar float64) {
+	p := k.p
+	if math.IsNaN(vel) {
+		s := p[0][0] + posVar
+		k0, k1 := p[0][0]/s, p[1][0]/s
+		y := pos - k.x[0]
+		k.x[0] += k0 * y
+		k.x[1] += k1 * y
+		k.p[0][0], k.p[0][1] = (1-k0)*p[0][0], (1-k0)*p[0][1]
+		k.p[1][0], k.p[1][1] = p[1][0]-k1*p[0][0], p[1][1]-k1*p[0][1]
+		return
+	}
+	// K = P (P + R)^-1 with R = diag(posVar, velVar).
+	s := [2][2]float64{{p[0][0] + posVar, p[0][1]}, {p[1][0], p[1][1] + velVar}}
+	det := s[0][0]*s[1][1] - s[0][1]*s[1][0]
+	si := [2][2]float64{{s[1][1] / det, -s[0][1] / det}, {-s[1][0] / det, s[0][0] / det}}
+	var kg [2][2]float64
+	for i := 0; i < 2; i++ {
+		for j := 0; j < 2; j++ {
+			kg[i][j] = p[i][0]*si[0][j] + p[i][1]*si[1][j]
+		}
+	}
+	y := [2]float64{pos - k.x[0], vel - k.x[1]}
+	for i := 0; i < 2; i++ {
+		k.x[i] += kg[i][0]*y[0] + kg[i][1]*y[1]
+	}
+	for i := 0; i < 2; i++ {
+		for j := 0; j < 2; j++ {
+			k.p[i][j] = p[i][j] - (kg[i][0]*p[0][j] + kg[i][1]*p[1][j])
+		}
+	}
+}
+
+// Estimate is a fix smoothed by a Filter.
+type Estimate struct {
+	fix Fix
+}
+
+func (e *Estimate) Fix() time.Time     { return e.fix.Time }
+func (e *Estimate) Longitude() float64 { return e.fix.Longitude }
+func (e *Estimate) Latitude() float64  { return e.fix.Latitude }
+func (e *Estimate) Speed() float64     { return e.fix.Speed }
+func (e *Estimate) Course() float64    { return e.fix.Course }
+func (e *Estimate) MagVar() float64    { return e.fix.MagVar }
+func (e *Estimate) Valid() bool        { return true }
+
+// Altitude is the height above mean sea level of the raw fix in meters.
+func (e *Estimate) Altitude() float64 { return e.fix.Altitude }
+
+// FilterNMEA filters the fixes of a stream. Accepted fixes become
+// estimates encoded as RMC sentences; rejected fixes and fixes without
+// a date, such as GGA, are dropped. Other messages pass through.
+func FilterNMEA(in <-chan NMEA, cfg FilterConfig) <-chan NMEA {
+	out := make(chan NMEA, 8)
+	go func() {
+		defer close(out)
+		f := NewFilter(cfg)
+		for msg := range in {
+			if msg.Valid() {
+				fix, ok := f.Update(msg)
+				if !ok {
+					continue
+				}
+				l := Encoder{Talker: msg.Talker()}.RMC(fix)
+				msg = NMEA{
+					// Match the line ending of parsed sentences.
+					line:  string(l[:len(l)-2]) + "\n",
+					NMEAi: &Estimate{fix},
+				}
+			}
+			out <- msg
+		}
+	}()
+	return out
+}
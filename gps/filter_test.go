@@ -0,0 +1,132 @@
+package gps
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bikeos/bosd/geo"
+)
+
+// filterTrack is a ride east at 8 m/s with noisy fixes and, every
+// tenth fix, a jump of 300m.
+func filterTrack(n int) (truth, raw []Fix) {
+	rnd := rand.New(rand.NewSource(1))
+	start := time.Date(2018, 3, 12, 2, 55, 3, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		lat, lon := 48.1173, 11.5167+float64(i)*8/(geo.EarthRadius*math.Cos(48.1173*math.Pi/180))*180/math.Pi
+		fix := Fix{
+			Time:      start.Add(time.Duration(i) * time.Second),
+			Valid:     true,
+			Latitude:  lat,
+			Longitude: lon,
+			Altitude:  math.NaN(),
+			Speed:     8,
+			Course:    90,
+			MagVar:    math.NaN(),
+		}
+		truth = append(truth, fix)
+		fix.Latitude += rnd.NormFloat64() * 5 / geo.EarthRadius * 180 / math.Pi
+		fix.Longitude += rnd.NormFloat64() * 5 / geo.EarthRadius * 180 / math.Pi
+		fix.Speed += rnd.NormFloat64() * 0.5
+		if i%10 == 9 {
+			fix.Latitude += 300 / geo.EarthRadius * 180 / math.Pi
+		}
+		raw = append(raw, fix)
+	}
+	return truth, raw
+}
+
+func fixDistance(a, b Fix) float64 {
+	return geo.Distance(geo.Point{Lat: a.Latitude, Lon: a.Longitude}, geo.Point{Lat: b.Latitude, Lon: b.Longitude})
+}
+
+func TestFilter(t *testing.T) {
+	truth, raw := filterTrack(100)
+	f := NewFilter(FilterConfig{})
+	var rawErr, filtErr, dist float64
+	var last *Fix
+	n := 0
+	for i, fix := range raw {
+		est, ok := f.Update(&Estimate{fix})
+		if jump := i%10 == 9; ok == jump {
+			t.Fatalf("#%d: expected accepted %v, got %v", i, !jump, ok)
+		}
+		if !ok {
+			continue
+		}
+		if last != nil {
+			dist += fixDistance(*last, est)
+		}
+		last = &est
+		if i < 10 {
+			// Let the filter settle.
+			continue
+		}
+		rawErr += math.Pow(fixDistance(fix, truth[i]), 2)
+		filtErr += math.Pow(fixDistance(est, truth[i]), 2)
+		n++
+	}
+	rawErr, filtErr = math.Sqrt(rawErr/float64(n)), math.Sqrt(filtErr/float64(n))
+	if filtErr > rawErr/2 {
+		t.Errorf("expected filtering to halve the error of %.2fm, got %.2fm", rawErr, filtErr)
+	}
+	if want := 99 * 8.0; math.Abs(dist-want) > want*0.05 {
+		t.Errorf("expected to travel %gm, got %gm", want, dist)
+	}
+	if math.Abs(last.Speed-8) > 0.5 || math.Abs(last.Course-90) > 5 {
+		t.Errorf("expected 8 m/s east, got %g m/s at %g", last.Speed, last.Course)
+	}
+}
+
+func TestFilterBadStart(t *testing.T) {
+	_, raw := filterTrack(20)
+	// Start with a fix a kilometer off.
+	bad := raw[0]
+	bad.Latitude += 1000 / geo.EarthRadius * 180 / math.Pi
+	bad.Time = bad.Time.Add(-time.Second)
+	f := NewFilter(FilterConfig{MaxRejects: 3})
+	if _, ok := f.Update(&Estimate{bad}); !ok {
+		t.Fatalf("expected first fix accepted")
+	}
+	for i, fix := range raw[:3] {
+		est, ok := f.Update(&Estimate{fix})
+		if ok != (i == 2) {
+			t.Fatalf("#%d: expected restart on third rejection", i)
+		}
+		if ok && fixDistance(est, fix) > 1 {
+			t.Errorf("expected restart at the fix, got %gm off", fixDistance(est, fix))
+		}
+	}
+	if _, ok := f.Update(&Estimate{raw[2]}); ok {
+		t.Errorf("expected repeated fix dropped")
+	}
+}
+
+func TestFilterNMEA(t *testing.T) {
+	route, _ := ReadGPX(strings.NewReader(simGPX))
+	g, err := NewSimGPS(SimConfig{Route: route, Speeds: []SimSpeed{{0, 10}}, Noise: 3, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rmcs := 0
+	for msg := range FilterNMEA(g.NMEA(), FilterConfig{}) {
+		if _, ok := msg.Msg().(*GGA); ok {
+			t.Fatalf("expected GGA dropped")
+		}
+		est, ok := msg.Msg().(*Estimate)
+		if !ok {
+			continue
+		}
+		rmcs++
+		p, ok := parseNMEA([]byte(strings.TrimSuffix(msg.Line(), "\n")))
+		if !ok || math.Abs(p.Latitude()-est.Latitude()) > 1e-6 || !p.Fix().Equal(est.Fix()) {
+			t.Errorf("bad estimate sentence %q for %+v", msg.Line(), est)
+		}
+	}
+	if rmcs != 21 {
+		t.Errorf("expected 21 fixes, got %d", rmcs)
+	}
+}
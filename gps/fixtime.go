@@ -0,0 +1,28 @@
+package gps
+
+import (
+	"fmt"
+	"time"
+)
+
+// gpsWeekRollover is the period of the 10-bit GPS week number.
+const gpsWeekRollover = 1024 * 7 * 24 * time.Hour
+
+// Fix times outside of this range come from receiver bugs. RMC dates
+// have two-digit years, and receivers that miss a week rollover report
+// dates 1024 weeks early.
+var (
+	minFixTime = time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	maxFixTime = minFixTime.Add(gpsWeekRollover)
+)
+
+// CheckFixTime returns an error if a fix time is implausible.
+func CheckFixTime(t time.Time) error {
+	if t.Before(minFixTime) {
+		return fmt.Errorf("gps: fix time %v predates %v; missed week rollover?", t, minFixTime)
+	}
+	if t.After(maxFixTime) {
+		return fmt.Errorf("gps: fix time %v is past %v; bad year?", t, maxFixTime)
+	}
+	return nil
+}
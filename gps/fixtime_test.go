@@ -0,0 +1,46 @@
+package gps
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckFixTime(t *testing.T) {
+	ride := time.Date(2018, 3, 12, 2, 55, 3, 0, time.UTC)
+	tts := []struct {
+		t  time.Time
+		ok bool
+	}{
+		{ride, true},
+		{time.Date(2037, 1, 1, 0, 0, 0, 0, time.UTC), true},
+		// Missed rollover.
+		{ride.Add(-gpsWeekRollover), false},
+		{time.Unix(0, 0), false},
+		// Two-digit years read as 20YY.
+		{time.Date(2098, 7, 28, 0, 0, 0, 0, time.UTC), false},
+	}
+	for i, tt := range tts {
+		if err := CheckFixTime(tt.t); (err == nil) != tt.ok {
+			t.Errorf("#%d: %v: expected ok %v, got %v", i, tt.t, tt.ok, err)
+		}
+	}
+}
+
+func TestCheckFixTimeRMC(t *testing.T) {
+	// A receiver that missed the 2019 rollover reports 1999 dates,
+	// which RMC's two-digit year turns into 2099.
+	fix := Fix{Time: time.Date(1999, 7, 28, 2, 55, 3, 0, time.UTC), Valid: true, MagVar: math.NaN()}
+	line := strings.TrimSpace(string(Encoder{}.RMC(fix)))
+	msg, ok := parseNMEA([]byte(line))
+	if !ok {
+		t.Fatalf("could not parse %q", line)
+	}
+	if msg.Fix().Year() != 2099 {
+		t.Fatalf("expected 2099, got %v", msg.Fix())
+	}
+	if err := CheckFixTime(msg.Fix()); err == nil {
+		t.Errorf("expected %v rejected", msg.Fix())
+	}
+}
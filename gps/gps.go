@@ -3,12 +3,76 @@ package gps
 import (
 	"bufio"
 	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
 	"io"
 	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
 )
 
+// maxLineLen bounds the length of a sentence. NMEA allows 82 characters;
+// leave room for proprietary sentences.
+const maxLineLen = 256
+
+// ErrChecksum is returned by a strict stream on a bad checksum.
+var ErrChecksum = errors.New("gps: bad checksum")
+
+// ChecksumPolicy decides what happens to sentences with bad checksums.
+type ChecksumPolicy int
+
+const (
+	// ChecksumDrop silently drops bad sentences.
+	ChecksumDrop ChecksumPolicy = iota
+	// ChecksumPass passes bad sentences with ChecksumOK unset. They
+	// are decoded with the same field checks as good sentences, so a
+	// damaged field makes the sentence unknown rather than malformed.
+	ChecksumPass
+	// ChecksumStrict closes the stream with ErrChecksum.
+	ChecksumStrict
+)
+
+var checksumPolicies = map[string]ChecksumPolicy{
+	"drop":   ChecksumDrop,
+	"pass":   ChecksumPass,
+	"strict": ChecksumStrict,
+}
+
+// ParseChecksumPolicy parses "drop", "pass", or "strict".
+func ParseChecksumPolicy(s string) (ChecksumPolicy, error) {
+	if cp, ok := checksumPolicies[s]; ok {
+		return cp, nil
+	}
+	return 0, fmt.Errorf("gps: unknown checksum policy %q", s)
+}
+
+// Config configures a GPS stream.
+type Config struct {
+	Checksum ChecksumPolicy
+	// Baud puts a serial device in raw mode at the given rate. Zero
+	// leaves the device as is; AutoBaud probes for the rate.
+	Baud int
+}
+
+// Stats counts messages read from a GPS stream.
+type Stats struct {
+	// Lines counts NMEA sentences and junk between messages.
+	Lines uint64
+	// Frames counts UBX frames.
+	Frames      uint64
+	BadChecksum uint64
+	Unparsable  uint64
+	Overlong    uint64
+}
+
 // GPS is an instance of an opened GPS stream.
 type GPS struct {
+	// stats is first for 64-bit atomic alignment on ARM.
+	stats  Stats
+	cfg    Config
+	baud   int
 	f      io.ReadCloser
 	ch     chan NMEA
 	err    error
@@ -18,30 +82,62 @@ type GPS struct {
 }
 
 // NewGPS opens a GPS stream from a given device path.
-func NewGPS(p string) (*GPS, error) {
-	f, err := os.Open(p)
+func NewGPS(p string) (*GPS, error) { return NewGPSWithConfig(p, Config{}) }
+
+// NewGPSWithConfig opens a configured GPS stream from a given device path.
+func NewGPSWithConfig(p string, cfg Config) (*GPS, error) {
+	if cfg.Baud == 0 {
+		f, err := os.Open(p)
+		if err != nil {
+			return nil, err
+		}
+		return newGPS(f, cfg), nil
+	}
+	f, baud, err := openSerial(p, cfg.Baud)
 	if err != nil {
 		return nil, err
 	}
-	return newGPS(f), nil
+	g := newGPS(f, cfg)
+	g.baud = baud
+	return g, nil
 }
 
-func newGPS(f io.ReadCloser) *GPS {
+func newGPS(f io.ReadCloser, cfg Config) *GPS {
+	ret := newStream(f, cfg)
+	go ret.read()
+	return ret
+}
+
+func newStream(f io.ReadCloser, cfg Config) *GPS {
 	ctx, cancel := context.WithCancel(context.Background())
-	ret := &GPS{
+	return &GPS{
+		cfg:    cfg,
 		f:      f,
 		ch:     make(chan NMEA),
 		donec:  make(chan struct{}),
 		ctx:    ctx,
 		cancel: cancel,
 	}
-	go ret.read()
-	return ret
 }
 
-// NMEA streams GPS messages. Closes on error.
+// NMEA streams GPS messages, including decoded UBX frames. Closes on
+// error.
 func (g *GPS) NMEA() <-chan NMEA { return g.ch }
 
+// Baud is the serial rate of the device; zero if not configured.
+func (g *GPS) Baud() int { return g.baud }
+
+// Stats returns the line counters of the stream.
+func (g *GPS) Stats() Stats {
+	return Stats{
+		Lines:       atomic.LoadUint64(&g.stats.Lines),
+		Frames:      atomic.LoadUint64(&g.stats.Frames),
+		BadChecksum: atomic.LoadUint64(&g.stats.BadChecksum),
+		Unparsable:  atomic.LoadUint64(&g.stats.Unparsable),
+		Overlong:    atomic.LoadUint64(&g.stats.Overlong),
+	}
+}
+
 // Close terminates the GPS stream and returns any error encountered.
 func (g *GPS) Close() error {
 	g.cancel()
@@ -51,32 +147,178 @@ func (g *GPS) Close() error {
 }
 
 func (g *GPS) read() {
+	r := bufio.NewReaderSize(g.f, maxLineLen)
+	g.run(func() (NMEA, error) { return g.next(r) })
+}
+
+// run streams messages until an error.
+func (g *GPS) run(next func() (NMEA, error)) {
 	defer func() {
 		close(g.ch)
 		close(g.donec)
 	}()
-	r := bufio.NewReader(g.f)
-	ng := &nmeaGrammar{}
-	ng.Init()
 	for g.err == nil {
-		line, pfx, err := r.ReadLine()
-		if pfx && err == nil {
-			err = io.ErrUnexpectedEOF
-		}
+		msg, err := next()
 		if err != nil {
 			g.err = err
 			break
 		}
-		ng.Buffer = string(line) + "\n"
-		ng.Reset()
-		if err = ng.Parse(); err != nil {
-			continue
-		}
-		ng.Execute()
+		msg.received = time.Now()
 		select {
-		case g.ch <- ng.nmea:
+		case g.ch <- msg:
 		case <-g.ctx.Done():
 			g.err = g.ctx.Err()
 		}
 	}
 }
+
+// next reads until the next message.
+func (g *GPS) next(r *bufio.Reader) (NMEA, error) {
+	junk := false
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return NMEA{}, err
+		}
+		var msg NMEA
+		var ok bool
+		switch {
+		case c == '$':
+			r.UnreadByte()
+			msg, ok, err = g.readNMEA(r)
+		case c == ubxSync1 && peekByte(r) == ubxSync2:
+			r.ReadByte()
+			msg, ok, err = g.readUBX(r)
+		default:
+			// Skip to the next message; count junk as a line.
+			if c == '\n' && junk {
+				g.countJunk()
+				junk = false
+			} else if c != '\r' && c != '\n' {
+				junk = true
+			}
+			continue
+		}
+		if junk {
+			g.countJunk()
+			junk = false
+		}
+		if err != nil || ok {
+			return msg, err
+		}
+	}
+}
+
+func peekByte(r *bufio.Reader) byte {
+	b, err := r.Peek(1)
+	if err != nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (g *GPS) countJunk() {
+	atomic.AddUint64(&g.stats.Lines, 1)
+	atomic.AddUint64(&g.stats.Unparsable, 1)
+}
+
+// badChecksum applies the checksum policy. Returns true if the message
+// should be dropped.
+func (g *GPS) badChecksum() (drop bool, err error) {
+	atomic.AddUint64(&g.stats.BadChecksum, 1)
+	switch g.cfg.Checksum {
+	case ChecksumStrict:
+		return true, ErrChecksum
+	case ChecksumDrop:
+		return true, nil
+	}
+	return false, nil
+}
+
+// readNMEA reads a sentence. Returns false if there is no message.
+func (g *GPS) readNMEA(r *bufio.Reader) (NMEA, bool, error) {
+	line, pfx, err := r.ReadLine()
+	if pfx && err == nil {
+		// Discard the rest of the line to resynchronize.
+		atomic.AddUint64(&g.stats.Overlong, 1)
+		for pfx && err == nil {
+			_, pfx, err = r.ReadLine()
+		}
+		return NMEA{}, false, err
+	}
+	if err != nil {
+		return NMEA{}, false, err
+	}
+	atomic.AddUint64(&g.stats.Lines, 1)
+	sumOK := checksumOK(line)
+	if !sumOK {
+		if drop, err := g.badChecksum(); drop {
+			return NMEA{}, false, err
+		}
+	}
+	msg, ok := parseNMEA(line)
+	if !ok {
+		atomic.AddUint64(&g.stats.Unparsable, 1)
+		return NMEA{}, false, nil
+	}
+	msg.badChecksum = !sumOK
+	return msg, true, nil
+}
+
+// readUBX reads a frame following its sync characters.
+func (g *GPS) readUBX(r *bufio.Reader) (NMEA, bool, error) {
+	hdr := [ubxHeaderLen]byte{ubxSync1, ubxSync2}
+	if _, err := io.ReadFull(r, hdr[2:]); err != nil {
+		return NMEA{}, false, err
+	}
+	n := int(binary.LittleEndian.Uint16(hdr[4:]))
+	if n > maxUBXPayload {
+		atomic.AddUint64(&g.stats.Overlong, 1)
+		return NMEA{}, false, nil
+	}
+	frame := make([]byte, ubxHeaderLen+n+2)
+	copy(frame, hdr[:])
+	if _, err := io.ReadFull(r, frame[ubxHeaderLen:]); err != nil {
+		return NMEA{}, false, err
+	}
+	atomic.AddUint64(&g.stats.Frames, 1)
+	ckA, ckB := ubxChecksum(frame[2 : len(frame)-2])
+	sumOK := ckA == frame[len(frame)-2] && ckB == frame[len(frame)-1]
+	if !sumOK {
+		if drop, err := g.badChecksum(); drop {
+			return NMEA{}, false, err
+		}
+	}
+	msg := NMEA{
+		ubx:         frame,
+		badChecksum: !sumOK,
+		NMEAi:       decodeUBX(frame[2], frame[3], frame[ubxHeaderLen:len(frame)-2]),
+	}
+	return msg, true, nil
+}
+
+// checksumOK checks the XOR of the characters between '$' and '*'
+// against the hex checksum following '*'.
+func checksumOK(line []byte) bool {
+	if len(line) < 4 || line[0] != '$' || line[len(line)-3] != '*' {
+		return false
+	}
+	want, err := strconv.ParseUint(string(line[len(line)-2:]), 16, 8)
+	if err != nil {
+		return false
+	}
+	var sum byte
+	for _, c := range line[1 : len(line)-3] {
+		sum ^= c
+	}
+	return sum == byte(want)
+}
+
+// nmeaSentence wraps a sentence body with its delimiters and checksum.
+func nmeaSentence(body string) []byte {
+	var sum byte
+	for i := 0; i < len(body); i++ {
+		sum ^= body[i]
+	}
+	return []byte(fmt.Sprintf("$%s*%02X\r\n", body, sum))
+}
@@ -2,6 +2,7 @@ package gps
 
 import (
 	"io"
+	"math"
 	"strings"
 	"testing"
 )
@@ -12,13 +13,13 @@ func (r *rc) Close() error { return nil }
 
 func TestRMCFix(t *testing.T) {
 	tts := []string{
-		"$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230318,003.1,W*6A\n",
-		"$GPRMC,025503.00,A,1111.22222,N,01111.33333,W,0.003,,120318,,,D*62\n",
+		"$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230318,003.1,W*6E\n",
+		"$GPRMC,025503.00,A,1111.22222,N,01111.33333,W,0.003,,120318,,,D*6D\n",
 		"$GPRMC,043019.00,V,,,,,,,120318,,,N*7B\n",
-		"$GPRMC,044735.00,A,2222.11111,N,02222.44444,W,1.339,,120318,,,A*6E\n",
+		"$GPRMC,044735.00,A,2222.11111,N,02222.44444,W,1.339,,120318,,,A*67\n",
 	}
 	for i, tt := range tts {
-		g := newGPS(&rc{strings.NewReader(tt)})
+		g := newGPS(&rc{strings.NewReader(tt)}, Config{})
 		msg, ok := <-g.NMEA()
 		if !ok {
 			t.Fatalf("#%d: expected message, got closed channel", i)
@@ -29,3 +30,227 @@ func TestRMCFix(t *testing.T) {
 		t.Log(msg.Fix())
 	}
 }
+
+func TestGGA(t *testing.T) {
+	tts := []struct {
+		line string
+		lat  float64
+		lon  float64
+		alt  float64
+		geo  float64
+		q    FixQuality
+		sats int
+		hdop float64
+	}{
+		{
+			"$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47\n",
+			48.1173, 11.516666666666667, 545.4, 46.9, FixGPS, 8, 0.9,
+		},
+		{
+			"$GNGGA,025503.00,1111.22222,S,01111.33333,W,2,12,0.78,-12.5,M,-30.1,M,1.0,0000*59\n",
+			-11.187037, -11.188889, -12.5, -30.1, FixDGPS, 12, 0.78,
+		},
+	}
+	for i, tt := range tts {
+		g := newGPS(&rc{strings.NewReader(tt.line)}, Config{})
+		msg, ok := <-g.NMEA()
+		if !ok {
+			t.Fatalf("#%d: expected message, got closed channel", i)
+		}
+		gga, ok := msg.Msg().(*GGA)
+		if !ok {
+			t.Fatalf("#%d: expected GGA, got %T", i, msg.Msg())
+		}
+		if d := gga.Latitude() - tt.lat; d > 1e-6 || d < -1e-6 {
+			t.Errorf("#%d: wanted lat %g, got %g", i, tt.lat, gga.Latitude())
+		}
+		if d := gga.Longitude() - tt.lon; d > 1e-6 || d < -1e-6 {
+			t.Errorf("#%d: wanted lon %g, got %g", i, tt.lon, gga.Longitude())
+		}
+		if gga.Altitude() != tt.alt {
+			t.Errorf("#%d: wanted alt %g, got %g", i, tt.alt, gga.Altitude())
+		}
+		if gga.GeoidSeparation() != tt.geo {
+			t.Errorf("#%d: wanted geoid sep %g, got %g", i, tt.geo, gga.GeoidSeparation())
+		}
+		if gga.Quality() != tt.q {
+			t.Errorf("#%d: wanted quality %d, got %d", i, tt.q, gga.Quality())
+		}
+		if gga.Satellites() != tt.sats {
+			t.Errorf("#%d: wanted %d sats, got %d", i, tt.sats, gga.Satellites())
+		}
+		if gga.HDOP() != tt.hdop {
+			t.Errorf("#%d: wanted hdop %g, got %g", i, tt.hdop, gga.HDOP())
+		}
+	}
+}
+
+func TestGGANoFix(t *testing.T) {
+	g := newGPS(&rc{strings.NewReader("$GPGGA,043019.00,,,,,0,00,99.99,,,,,,*69\n")}, Config{})
+	msg := <-g.NMEA()
+	gga, ok := msg.Msg().(*GGA)
+	if !ok {
+		t.Fatalf("expected GGA, got %T", msg.Msg())
+	}
+	if gga.Quality() != FixInvalid {
+		t.Errorf("expected invalid fix, got %d", gga.Quality())
+	}
+	if lat := gga.Latitude(); lat == lat {
+		t.Errorf("expected NaN latitude, got %g", lat)
+	}
+	if alt := gga.Altitude(); alt == alt {
+		t.Errorf("expected NaN altitude, got %g", alt)
+	}
+}
+
+func TestChecksumPolicy(t *testing.T) {
+	lines := "$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230318,003.1,W*6A\n" +
+		"$GPRMC,043019.00,V,,,,,,,120318,,,N*7B\n"
+	tts := []struct {
+		policy ChecksumPolicy
+		msgs   int
+		bad    int
+		err    error
+	}{
+		{ChecksumDrop, 1, 0, io.EOF},
+		{ChecksumPass, 2, 1, io.EOF},
+		{ChecksumStrict, 0, 0, ErrChecksum},
+	}
+	for i, tt := range tts {
+		g := newGPS(&rc{strings.NewReader(lines)}, Config{Checksum: tt.policy})
+		msgs, bad := 0, 0
+		for msg := range g.NMEA() {
+			msgs++
+			if !msg.ChecksumOK() {
+				bad++
+			}
+		}
+		if msgs != tt.msgs || bad != tt.bad {
+			t.Errorf("#%d: wanted %d msgs (%d bad), got %d (%d bad)", i, tt.msgs, tt.bad, msgs, bad)
+		}
+		if err := g.Close(); err != tt.err {
+			t.Errorf("#%d: wanted error %v, got %v", i, tt.err, err)
+		}
+		if n := g.Stats().BadChecksum; n != 1 {
+			t.Errorf("#%d: expected 1 bad checksum, got %d", i, n)
+		}
+	}
+}
+
+// TestChecksumPassDamaged checks that passed sentences with damaged
+// fields do not decode.
+func TestChecksumPassDamaged(t *testing.T) {
+	lines := "$GPRMC,1,A,4807.038,N,01131.000,E,022.4,084.4,230318,003.1,W*6E\n" +
+		"$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,2303,003.1,W*6E\n" +
+		"$GPGGA,123519,4807.038,N,01131.000,E,1,08888888888,0.9,545.4,M,46.9,M,,*47\n" +
+		"$GPGSV,2,1,08,01,40,083,46,02,17,308,41,12,07,344,39,14,22,228,45555555555*75\n"
+	g := newGPS(&rc{strings.NewReader(lines)}, Config{Checksum: ChecksumPass})
+	msgs := 0
+	for msg := range g.NMEA() {
+		msgs++
+		if msg.ChecksumOK() {
+			t.Errorf("expected bad checksum on %q", msg.Line())
+		}
+		if _, ok := msg.Msg().(*nmeaUnk); !ok {
+			t.Errorf("expected unknown message for %q, got %T", msg.Line(), msg.Msg())
+		}
+		if !msg.Fix().IsZero() {
+			t.Errorf("expected zero fix for %q", msg.Line())
+		}
+	}
+	if msgs != 4 {
+		t.Errorf("wanted 4 msgs, got %d", msgs)
+	}
+}
+
+func TestOverlongResync(t *testing.T) {
+	lines := "$GPRMC," + strings.Repeat("9", 2*maxLineLen) + "*00\n" +
+		"garbage\n" +
+		"$GPRMC,043019.00,V,,,,,,,120318,,,N*7B\n"
+	g := newGPS(&rc{strings.NewReader(lines)}, Config{Checksum: ChecksumPass})
+	msg, ok := <-g.NMEA()
+	if !ok {
+		t.Fatalf("expected message after over-long line, got %v", g.Close())
+	}
+	if msg.Line() != "$GPRMC,043019.00,V,,,,,,,120318,,,N*7B\n" {
+		t.Errorf("unexpected line %q", msg.Line())
+	}
+	g.Close()
+	want := Stats{Lines: 2, Unparsable: 1, Overlong: 1}
+	if st := g.Stats(); st != want {
+		t.Errorf("wanted %+v, got %+v", want, st)
+	}
+}
+
+func TestRMCMotion(t *testing.T) {
+	tts := []struct {
+		line   string
+		speed  float64
+		course float64
+		magvar float64
+		valid  bool
+	}{
+		{
+			"$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230318,003.1,W*6E\n",
+			11.523555, 84.4, -3.1, true,
+		},
+		{
+			"$GPRMC,044735.00,A,2222.11111,N,02222.44444,W,1.339,,120318,,,A*67\n",
+			0.688841, math.NaN(), math.NaN(), true,
+		},
+		{
+			"$GPRMC,043019.00,V,,,,,,,120318,,,N*7B\n",
+			math.NaN(), math.NaN(), math.NaN(), false,
+		},
+	}
+	near := func(a, b float64) bool {
+		if a != a || b != b {
+			return a != a && b != b
+		}
+		return math.Abs(a-b) < 1e-6
+	}
+	for i, tt := range tts {
+		g := newGPS(&rc{strings.NewReader(tt.line)}, Config{})
+		msg, ok := <-g.NMEA()
+		if !ok {
+			t.Fatalf("#%d: expected message, got closed channel", i)
+		}
+		if !near(msg.Speed(), tt.speed) {
+			t.Errorf("#%d: wanted speed %g, got %g", i, tt.speed, msg.Speed())
+		}
+		if !near(msg.Course(), tt.course) {
+			t.Errorf("#%d: wanted course %g, got %g", i, tt.course, msg.Course())
+		}
+		if !near(msg.MagVar(), tt.magvar) {
+			t.Errorf("#%d: wanted magvar %g, got %g", i, tt.magvar, msg.MagVar())
+		}
+		if msg.Valid() != tt.valid {
+			t.Errorf("#%d: wanted valid=%v", i, tt.valid)
+		}
+	}
+}
+
+func TestRMCFixSubSecond(t *testing.T) {
+	tts := []struct {
+		line string
+		ns   int
+	}{
+		{"$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230318,003.1,W*6E\n", 0},
+		{"$GPRMC,044735.00,A,2222.11111,N,02222.44444,W,1.339,,120318,,,A*67\n", 0},
+		{"$GPRMC,044735.2,A,2222.11111,N,02222.44444,W,1.339,,120318,,,A*55\n", 200000000},
+		{"$GPRMC,044735.125,A,2222.11111,N,02222.44444,W,1.339,,120318,,,A*51\n", 125000000},
+	}
+	for i, tt := range tts {
+		g := newGPS(&rc{strings.NewReader(tt.line)}, Config{})
+		msg, ok := <-g.NMEA()
+		if !ok {
+			t.Fatalf("#%d: expected message, got closed channel", i)
+		}
+		if ns := msg.Fix().Nanosecond(); ns != tt.ns {
+			t.Errorf("#%d: wanted %dns, got %dns", i, tt.ns, ns)
+		}
+		if s := msg.Fix().Second(); s != 35 && s != 19 {
+			t.Errorf("#%d: wrong second %d", i, s)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package gps
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// gpsdWatch asks gpsd to stream JSON reports.
+const gpsdWatch = `?WATCH={"enable":true,"json":true};` + "\n"
+
+// maxReportLen bounds the length of a gpsd report; SKY reports list
+// every satellite.
+const maxReportLen = 16384
+
+// NewGPSD streams reports from a gpsd instance. The address is a TCP
+// host and port (e.g., "localhost:2947") or a unix socket path.
+func NewGPSD(addr string) (*GPS, error) {
+	network := "tcp"
+	if strings.HasPrefix(addr, "/") {
+		network = "unix"
+	}
+	conn, err := net.Dial(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(conn, gpsdWatch); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	g := newStream(conn, Config{})
+	r := bufio.NewReaderSize(conn, maxReportLen)
+	go g.run(func() (NMEA, error) { return g.nextReport(r) })
+	return g, nil
+}
+
+// nextReport reads until the next gpsd report.
+func (g *GPS) nextReport(r *bufio.Reader) (NMEA, error) {
+	for {
+		line, pfx, err := r.ReadLine()
+		if pfx && err == nil {
+			atomic.AddUint64(&g.stats.Overlong, 1)
+			for pfx && err == nil {
+				_, pfx, err = r.ReadLine()
+			}
+			continue
+		}
+		if err != nil {
+			return NMEA{}, err
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		atomic.AddUint64(&g.stats.Lines, 1)
+		msg, err := decodeReport(line)
+		if err != nil {
+			atomic.AddUint64(&g.stats.Unparsable, 1)
+			continue
+		}
+		return msg, nil
+	}
+}
+
+func decodeReport(line []byte) (NMEA, error) {
+	var hdr struct {
+		Class string `json:"class"`
+	}
+	if err := json.Unmarshal(line, &hdr); err != nil {
+		return NMEA{}, err
+	}
+	var msg NMEAi = &nmeaUnk{}
+	switch hdr.Class {
+	case "TPV":
+		tpv := &TPV{}
+		if err := json.Unmarshal(line, &tpv.r); err != nil {
+			return NMEA{}, err
+		}
+		msg = tpv
+	case "SKY":
+		sky := &SKY{}
+		if err := json.Unmarshal(line, &sky.r); err != nil {
+			return NMEA{}, err
+		}
+		msg = sky
+	}
+	report := append(append([]byte{}, line...), '\n')
+	return NMEA{json: report, NMEAi: msg}, nil
+}
+
+func optFloat64(f *float64) float64 {
+	if f == nil {
+		return math.NaN()
+	}
+	return *f
+}
+
+// TPV is a gpsd time-position-velocity report.
+type TPV struct {
+	r struct {
+		Class  string   `json:"class"`
+		Device string   `json:"device,omitempty"`
+		Mode   int      `json:"mode"`
+		Time   string   `json:"time,omitempty"`
+		Lat    *float64 `json:"lat,omitempty"`
+		Lon    *float64 `json:"lon,omitempty"`
+		Alt    *float64 `json:"alt,omitempty"`
+		AltMSL *float64 `json:"altMSL,omitempty"`
+		Speed  *float64 `json:"speed,omitempty"`
+		Track  *float64 `json:"track,omitempty"`
+		Magvar *float64 `json:"magvar,omitempty"`
+	}
+}
+
+// NewTPV makes a report of a fix from a device.
+func NewTPV(device string, mode FixMode, fix NMEAi) *TPV {
+	if n, ok := fix.(NMEA); ok {
+		fix = n.Msg()
+	}
+	t := &TPV{}
+	t.r.Class, t.r.Device, t.r.Mode = "TPV", device, int(mode)
+	if ft := fix.Fix(); !ft.IsZero() {
+		t.r.Time = ft.UTC().Format("2006-01-02T15:04:05.000Z")
+	}
+	t.r.Lat, t.r.Lon = reportFloat64(fix.Latitude()), reportFloat64(fix.Longitude())
+	t.r.Speed, t.r.Track = reportFloat64(fix.Speed()), reportFloat64(fix.Course())
+	t.r.Magvar = reportFloat64(fix.MagVar())
+	if a, ok := fix.(interface{ Altitude() float64 }); ok {
+		t.r.AltMSL = reportFloat64(a.Altitude())
+		t.r.Alt = t.r.AltMSL
+	}
+	return t
+}
+
+// reportFloat64 omits unknown values from reports.
+func reportFloat64(f float64) *float64 {
+	if math.IsNaN(f) {
+		return nil
+	}
+	return &f
+}
+
+// MarshalJSON encodes the report as gpsd does.
+func (t *TPV) MarshalJSON() ([]byte, error) { return json.Marshal(&t.r) }
+
+func (t *TPV) Fix() time.Time {
+	ret, err := time.Parse(time.RFC3339Nano, t.r.Time)
+	if err != nil {
+		return time.Time{}
+	}
+	return ret
+}
+
+func (t *TPV) Longitude() float64 { return optFloat64(t.r.Lon) }
+func (t *TPV) Latitude() float64  { return optFloat64(t.r.Lat) }
+func (t *TPV) Speed() float64     { return optFloat64(t.r.Speed) }
+func (t *TPV) Course() float64    { return optFloat64(t.r.Track) }
+func (t *TPV) MagVar() float64    { return optFloat64(t.r.Magvar) }
+func (t *TPV) Valid() bool        { return t.Mode() >= Fix2D }
+
+// Mode is the fix mode; zero if gpsd has not seen the receiver's mode.
+func (t *TPV) Mode() FixMode { return FixMode(t.r.Mode) }
+
+// Device is the path of the receiver reporting the fix.
+func (t *TPV) Device() string { return t.r.Device }
+
+// Altitude is the height above mean sea level in meters.
+func (t *TPV) Altitude() float64 {
+	if t.r.AltMSL != nil {
+		return *t.r.AltMSL
+	}
+	// Older gpsd only reports alt, which is above mean sea level.
+	return optFloat64(t.r.Alt)
+}
+
+// SKY is a gpsd sky view report.
+type SKY struct {
+	nmeaUnk
+	r struct {
+		Device     string   `json:"device"`
+		HDOP       *float64 `json:"hdop"`
+		VDOP       *float64 `json:"vdop"`
+		PDOP       *float64 `json:"pdop"`
+		Satellites []struct {
+			PRN    int     `json:"PRN"`
+			GNSSID *byte   `json:"gnssid"`
+			SVID   int     `json:"svid"`
+			El     float64 `json:"el"`
+			Az     float64 `json:"az"`
+			SS     float64 `json:"ss"`
+			Used   bool    `json:"used"`
+		} `json:"satellites"`
+	}
+}
+
+func (s *SKY) HDOP() float64 { return optFloat64(s.r.HDOP) }
+func (s *SKY) VDOP() float64 { return optFloat64(s.r.VDOP) }
+func (s *SKY) PDOP() float64 { return optFloat64(s.r.PDOP) }
+
+// Satellites lists the satellites in view.
+func (s *SKY) Satellites() []Satellite {
+	ret := make([]Satellite, len(s.r.Satellites))
+	for i, sat := range s.r.Satellites {
+		ret[i] = Satellite{
+			Talker:    "GN",
+			PRN:       sat.PRN,
+			Elevation: int(math.Round(sat.El)),
+			Azimuth:   int(math.Round(sat.Az)),
+			SNR:       int(math.Round(sat.SS)),
+			Active:    sat.Used,
+		}
+		// gpsd numbers constellations like UBX.
+		if sat.GNSSID == nil {
+			continue
+		}
+		if talker, ok := ubxTalkers[*sat.GNSSID]; ok {
+			ret[i].Talker, ret[i].PRN = talker, sat.SVID
+		}
+	}
+	return ret
+}
@@ -0,0 +1,139 @@
+package gps
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"math"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var gpsdReports = []string{
+	`{"class":"VERSION","release":"3.17","rev":"3.17","proto_major":3,"proto_minor":12}`,
+	`{"class":"DEVICES","devices":[{"class":"DEVICE","path":"/dev/ttyACM0","driver":"u-blox"}]}`,
+	`{"class":"WATCH","enable":true,"json":true}`,
+	`{"class":"SKY","device":"/dev/ttyACM0","hdop":0.9,"vdop":1.5,"pdop":1.8,"satellites":[` +
+		`{"PRN":14,"gnssid":0,"svid":14,"el":22,"az":228,"ss":45,"used":true},` +
+		`{"PRN":65,"gnssid":6,"svid":1,"el":30,"az":100,"ss":35,"used":false},` +
+		`{"PRN":17,"el":55,"az":120,"ss":0,"used":false}]}`,
+	`{"class":"TPV","device":"/dev/ttyACM0","mode":3,"time":"2018-03-12T02:55:03.250Z",` +
+		`"lat":48.1173,"lon":-111.1888889,"altMSL":545.4,"speed":5.5,"track":84.4}`,
+	`{"class":"TPV",`,
+	`{"class":"TPV","device":"/dev/ttyACM0","mode":1}`,
+}
+
+// fakeGPSD serves reports to the first client once it asks to watch.
+func fakeGPSD(t *testing.T, l net.Listener) {
+	conn, err := l.Accept()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer conn.Close()
+	cmd, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || !strings.HasPrefix(cmd, "?WATCH=") {
+		t.Errorf("expected watch command, got %q (%v)", cmd, err)
+		return
+	}
+	io.WriteString(conn, strings.Join(gpsdReports, "\r\n")+"\r\n")
+}
+
+func TestGPSD(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "gpsd.sock")
+	for _, network := range []string{"tcp", "unix"} {
+		addr := "127.0.0.1:0"
+		if network == "unix" {
+			addr = sock
+		}
+		l, err := net.Listen(network, addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		go fakeGPSD(t, l)
+		g, err := NewGPSD(l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var msgs []NMEA
+		for msg := range g.NMEA() {
+			msgs = append(msgs, msg)
+		}
+		l.Close()
+		if err := g.Close(); err != io.EOF {
+			t.Errorf("%s: expected EOF, got %v", network, err)
+		}
+		testGPSDMessages(t, msgs)
+		want := Stats{Lines: 7, Unparsable: 1}
+		if st := g.Stats(); st != want {
+			t.Errorf("%s: wanted %+v, got %+v", network, want, st)
+		}
+	}
+}
+
+func testGPSDMessages(t *testing.T, msgs []NMEA) {
+	if len(msgs) != 6 {
+		t.Fatalf("expected 6 reports, got %d", len(msgs))
+	}
+	if string(msgs[0].JSON()) != gpsdReports[0]+"\n" || msgs[0].Line() != "" {
+		t.Errorf("expected raw report, got %q", msgs[0].JSON())
+	}
+	tpv, ok := msgs[4].Msg().(*TPV)
+	if !ok {
+		t.Fatalf("expected TPV, got %T", msgs[4].Msg())
+	}
+	fix := time.Date(2018, 3, 12, 2, 55, 3, 250000000, time.UTC)
+	if !tpv.Valid() || !tpv.Fix().Equal(fix) || tpv.Mode() != Fix3D {
+		t.Errorf("wrong fix %v mode %d", tpv.Fix(), tpv.Mode())
+	}
+	if tpv.Latitude() != 48.1173 || tpv.Longitude() != -111.1888889 || tpv.Altitude() != 545.4 {
+		t.Errorf("wrong position %g,%g,%g", tpv.Latitude(), tpv.Longitude(), tpv.Altitude())
+	}
+	if tpv.Speed() != 5.5 || tpv.Course() != 84.4 || !math.IsNaN(tpv.MagVar()) {
+		t.Errorf("wrong motion %g/%g/%g", tpv.Speed(), tpv.Course(), tpv.MagVar())
+	}
+	if nofix := msgs[5]; nofix.Valid() || !nofix.Fix().IsZero() || !math.IsNaN(nofix.Latitude()) {
+		t.Errorf("expected no fix from mode 1 report")
+	}
+
+	sv := NewSkyView()
+	for _, msg := range msgs[:5] {
+		sv.Update(msg)
+	}
+	sky := sv.Sky()
+	if sky.Mode != Fix3D || sky.PDOP != 1.8 || sky.HDOP != 0.9 || sky.VDOP != 1.5 {
+		t.Errorf("wrong geometry %+v", sky)
+	}
+	want := []Satellite{
+		{Talker: "GL", PRN: 1, Elevation: 30, Azimuth: 100, SNR: 35},
+		{Talker: "GN", PRN: 17, Elevation: 55, Azimuth: 120},
+		{Talker: "GP", PRN: 14, Elevation: 22, Azimuth: 228, SNR: 45, Active: true},
+	}
+	if len(sky.Satellites) != len(want) {
+		t.Fatalf("wanted %+v, got %+v", want, sky.Satellites)
+	}
+	for i := range want {
+		if sky.Satellites[i] != want[i] {
+			t.Errorf("wanted %+v, got %+v", want[i], sky.Satellites[i])
+		}
+	}
+}
+
+func TestTPVRoundTrip(t *testing.T) {
+	msg, err := decodeReport([]byte(gpsdReports[4]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(NewTPV("/dev/ttyACM0", Fix3D, msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"class":"TPV","device":"/dev/ttyACM0","mode":3,"time":"2018-03-12T02:55:03.250Z",` +
+		`"lat":48.1173,"lon":-111.1888889,"alt":545.4,"altMSL":545.4,"speed":5.5,"track":84.4}`
+	if string(b) != want {
+		t.Errorf("wanted %s, got %s", want, b)
+	}
+}
@@ -1,4 +1,3 @@
-//go:generate peg -strict nmea.peg
 package gps
 
 import (
@@ -8,97 +7,377 @@ import (
 )
 
 type NMEA struct {
-	line string
+	line        string
+	ubx         []byte
+	json        []byte
+	badChecksum bool
+	received    time.Time
 	NMEAi
 }
 
 func (n NMEA) Msg() NMEAi { return n.NMEAi }
 
-// Line returns the raw NMEA string.
+// ChecksumOK is false if the sentence was passed with a bad checksum.
+func (n NMEA) ChecksumOK() bool { return !n.badChecksum }
+
+// Line returns the raw NMEA string; empty for UBX frames and gpsd
+// reports.
 func (n NMEA) Line() string { return n.line }
 
+// UBX returns the raw UBX frame; nil otherwise.
+func (n NMEA) UBX() []byte { return n.ubx }
+
+// JSON returns the raw gpsd report; nil otherwise.
+func (n NMEA) JSON() []byte { return n.json }
+
+// Received is the local time the message was read from its stream; zero
+// for messages made by other means.
+func (n NMEA) Received() time.Time { return n.received }
+
+// Talker returns the talker id of the sentence (e.g., "GP", "GN"), or
+// "P" for proprietary sentences.
+func (n NMEA) Talker() string {
+	if len(n.line) < 3 {
+		return ""
+	}
+	if n.line[1] == 'P' {
+		return "P"
+	}
+	return n.line[1:3]
+}
+
 type NMEAi interface {
 	Fix() time.Time
 	Longitude() float64
 	Latitude() float64
+	// Speed is the ground speed in m/s.
+	Speed() float64
+	// Course is the true course over ground in degrees.
+	Course() float64
+	// MagVar is the magnetic variation in degrees; east is positive.
+	MagVar() float64
+	// Valid is set if the message carries a usable fix.
+	Valid() bool
 }
 
+// nmeaUnk is a message without fix data. Messages that only carry
+// some fix data embed it for the rest.
 type nmeaUnk struct{}
 
 func (n *nmeaUnk) Fix() (ret time.Time) { return ret }
 func (n *nmeaUnk) Longitude() float64   { return math.NaN() }
 func (n *nmeaUnk) Latitude() float64    { return math.NaN() }
+func (n *nmeaUnk) Speed() float64       { return math.NaN() }
+func (n *nmeaUnk) Course() float64      { return math.NaN() }
+func (n *nmeaUnk) MagVar() float64      { return math.NaN() }
+func (n *nmeaUnk) Valid() bool          { return false }
 
 type RMC struct {
-	fix    string
-	status string
-	lat    string
-	ns     string
-	lon    string
-	we     string
-	knots  Knots
-	track  string
-	date   string
-	magvar string
-	chksum string
+	fix      string
+	status   string
+	lat      string
+	ns       string
+	lon      string
+	we       string
+	knots    string
+	track    string
+	date     string
+	magvar   string
+	magvarwe string
+	chksum   string
 }
 
 type Knots float64
 
-func (k *Knots) parse(text string) { *k = Knots(mustFloat64(text)) }
+// MetersPerSecond converts knots to m/s.
+func (k Knots) MetersPerSecond() float64 { return float64(k) * 1852.0 / 3600.0 }
 
-func mustFloat64(text string) float64 {
+// toFloat64 parses a numeric field; NaN if malformed.
+func toFloat64(text string) float64 {
 	ret, err := strconv.ParseFloat(text, 64)
 	if err != nil {
-		panic(err)
+		return math.NaN()
 	}
 	return ret
 }
 
-func mustInt(text string) int {
+// toInt parses an integer field; zero if malformed.
+func toInt(text string) int {
 	ret, err := strconv.ParseInt(text, 10, 32)
 	if err != nil {
-		panic(err)
+		return 0
 	}
 	return int(ret)
 }
 
-func (r *RMC) Fix() time.Time {
-	// DD/MM/YY HH:MM:SS
+// Fix is the UTC time of the fix; zero if the time or date is
+// malformed.
+func (r *RMC) Fix() (ret time.Time) {
+	if len(r.date) != 6 || len(r.fix) < 6 {
+		return ret
+	}
+	// DD/MM/YY HH:MM:SS.SS
 	return time.Date(
-		2000+mustInt(r.date[4:6]),
-		time.Month(mustInt(r.date[2:4])),
-		mustInt(r.date[:2]),
-		mustInt(r.fix[:2]),
-		mustInt(r.fix[2:4]),
-		mustInt(r.fix[4:6]),
-		0,
+		2000+toInt(r.date[4:6]),
+		time.Month(toInt(r.date[2:4])),
+		toInt(r.date[:2]),
+		toInt(r.fix[:2]),
+		toInt(r.fix[2:4]),
+		toInt(r.fix[4:6]),
+		fixNanos(r.fix[6:]),
 		time.UTC)
 }
 
-func (r *RMC) Longitude() float64 {
-	if len(r.lon) == 0 {
+// fixNanos converts the fractional seconds of a fix (e.g., ".25") to
+// nanoseconds.
+func fixNanos(frac string) int {
+	if len(frac) < 2 {
+		return 0
+	}
+	// Pad or truncate to nine digits.
+	return toInt((frac[1:] + "000000000")[:9])
+}
+
+func (r *RMC) Longitude() float64 { return parseLongitude(r.lon, r.we) }
+func (r *RMC) Latitude() float64  { return parseLatitude(r.lat, r.ns) }
+
+// Knots is the ground speed in knots; NaN if not given.
+func (r *RMC) Knots() Knots    { return Knots(parseFloat64(r.knots)) }
+func (r *RMC) Speed() float64  { return r.Knots().MetersPerSecond() }
+func (r *RMC) Course() float64 { return parseFloat64(r.track) }
+func (r *RMC) Valid() bool     { return r.status == "A" }
+
+func (r *RMC) MagVar() float64 {
+	mv := parseFloat64(r.magvar)
+	if r.magvarwe == "W" {
+		mv = -mv
+	}
+	return mv
+}
+
+// FixQuality is the GGA fix quality indicator.
+type FixQuality int
+
+const (
+	FixInvalid FixQuality = iota
+	FixGPS
+	FixDGPS
+	FixPPS
+	FixRTK
+	FixFloatRTK
+	FixEstimated
+	FixManual
+	FixSimulation
+)
+
+// GGA is the fix data sentence. It carries the 3D location and
+// accuracy of a fix but no date, so Fix is always zero.
+type GGA struct {
+	nmeaUnk
+	fix         string
+	lat         string
+	ns          string
+	lon         string
+	we          string
+	quality     string
+	sats        string
+	hdop        string
+	alt         string
+	geoidsep    string
+	dgpsAge     string
+	dgpsStation string
+	chksum      string
+}
+
+func (g *GGA) Longitude() float64 { return parseLongitude(g.lon, g.we) }
+func (g *GGA) Latitude() float64  { return parseLatitude(g.lat, g.ns) }
+func (g *GGA) Valid() bool        { return g.Quality() != FixInvalid }
+
+// Altitude is the antenna height above mean sea level in meters.
+func (g *GGA) Altitude() float64 { return parseFloat64(g.alt) }
+
+// GeoidSeparation is the height of mean sea level above the WGS84
+// ellipsoid in meters.
+func (g *GGA) GeoidSeparation() float64 { return parseFloat64(g.geoidsep) }
+
+// Quality is the fix quality; FixInvalid if there is no fix.
+func (g *GGA) Quality() FixQuality {
+	if len(g.quality) == 0 {
+		return FixInvalid
+	}
+	return FixQuality(toInt(g.quality))
+}
+
+// Satellites is the number of satellites in use.
+func (g *GGA) Satellites() int { return parseInt(g.sats) }
+
+// HDOP is the horizontal dilution of precision.
+func (g *GGA) HDOP() float64 { return parseFloat64(g.hdop) }
+
+// Satellite is a satellite reported in view by a GSV sentence.
+type Satellite struct {
+	// Talker is the constellation talker id (e.g., "GP", "GL").
+	Talker string
+	PRN    int
+	// Elevation and Azimuth are in degrees.
+	Elevation int
+	Azimuth   int
+	// SNR is the signal to noise ratio in dB-Hz; zero if not tracking.
+	SNR int
+	// Active is set if the satellite is used in the fix.
+	Active bool
+}
+
+type gsvSat struct {
+	prn  string
+	elev string
+	az   string
+	snr  string
+}
+
+// GSV is one sentence of a satellites in view group.
+type GSV struct {
+	nmeaUnk
+	total  string
+	num    string
+	inview string
+	sats   []gsvSat
+	signal string
+	chksum string
+}
+
+// Sentences is the number of sentences in the group.
+func (g *GSV) Sentences() int { return toInt(g.total) }
+
+// Sentence is the 1-based index of this sentence in the group.
+func (g *GSV) Sentence() int { return toInt(g.num) }
+
+// InView is the total number of satellites in view.
+func (g *GSV) InView() int { return toInt(g.inview) }
+
+// Signal is the NMEA 4.1 signal id; empty if not given.
+func (g *GSV) Signal() string { return g.signal }
+
+// Satellites are the satellites listed by this sentence.
+func (g *GSV) Satellites() []Satellite {
+	ret := make([]Satellite, len(g.sats))
+	for i, s := range g.sats {
+		ret[i] = Satellite{
+			PRN:       toInt(s.prn),
+			Elevation: parseInt(s.elev),
+			Azimuth:   parseInt(s.az),
+			SNR:       parseInt(s.snr),
+		}
+	}
+	return ret
+}
+
+// FixMode is the GSA fix mode.
+type FixMode int
+
+const (
+	FixNone FixMode = iota + 1
+	Fix2D
+	Fix3D
+)
+
+// GSA is the DOP and active satellites sentence.
+type GSA struct {
+	nmeaUnk
+	mode    string
+	fixMode string
+	prns    []string
+	pdop    string
+	hdop    string
+	vdop    string
+	system  string
+	chksum  string
+}
+
+// Auto is set if the receiver picks between 2D and 3D fixes.
+func (g *GSA) Auto() bool { return g.mode == "A" }
+
+func (g *GSA) Mode() FixMode { return FixMode(toInt(g.fixMode)) }
+
+// PRNs are the satellites used in the fix.
+func (g *GSA) PRNs() []int {
+	ret := make([]int, len(g.prns))
+	for i, prn := range g.prns {
+		ret[i] = toInt(prn)
+	}
+	return ret
+}
+
+func (g *GSA) PDOP() float64 { return parseFloat64(g.pdop) }
+func (g *GSA) HDOP() float64 { return parseFloat64(g.hdop) }
+func (g *GSA) VDOP() float64 { return parseFloat64(g.vdop) }
+
+// System is the NMEA 4.1 GNSS system id; empty if not given.
+func (g *GSA) System() string { return g.system }
+
+// VTG is the course and speed over ground sentence. It carries no
+// position, so it is never a valid fix on its own.
+type VTG struct {
+	nmeaUnk
+	track    string
+	magtrack string
+	knots    string
+	kmh      string
+	mode     string
+	chksum   string
+}
+
+// Knots is the ground speed in knots; NaN if not given.
+func (v *VTG) Knots() Knots    { return Knots(parseFloat64(v.knots)) }
+func (v *VTG) Speed() float64  { return v.Knots().MetersPerSecond() }
+func (v *VTG) Course() float64 { return parseFloat64(v.track) }
+
+// MagneticCourse is the magnetic course over ground in degrees.
+func (v *VTG) MagneticCourse() float64 { return parseFloat64(v.magtrack) }
+
+// Mode is the NMEA 2.3 positioning mode (e.g., "A", "N"); empty if not
+// given.
+func (v *VTG) Mode() string { return v.mode }
+
+// parseFloat64 parses an optional numeric field; NaN if missing.
+func parseFloat64(text string) float64 {
+	if len(text) == 0 {
+		return math.NaN()
+	}
+	return toFloat64(text)
+}
+
+// parseInt parses an optional integer field; zero if missing.
+func parseInt(text string) int {
+	if len(text) == 0 {
+		return 0
+	}
+	return toInt(text)
+}
+
+func parseLongitude(lon, we string) float64 {
+	if len(lon) == 0 {
 		return math.NaN()
 	}
 	// DDDMM.MMMMM,W = longitude
-	londeg, _ := strconv.ParseInt(r.lon[0:3], 10, 32)
-	lonmin, _ := strconv.ParseFloat(r.lon[3:], 64)
+	londeg, _ := strconv.ParseInt(lon[0:3], 10, 32)
+	lonmin, _ := strconv.ParseFloat(lon[3:], 64)
 	lonv := float64(londeg) + (lonmin / 60.0)
-	if r.we == "W" {
+	if we == "W" {
 		lonv = -lonv
 	}
 	return lonv
 }
 
-func (r *RMC) Latitude() float64 {
-	if len(r.lat) == 0 {
+func parseLatitude(lat, ns string) float64 {
+	if len(lat) == 0 {
 		return math.NaN()
 	}
 	// DDMM.MMMMM,N = latitude
-	latdeg, _ := strconv.ParseInt(r.lat[0:2], 10, 32)
-	latmin, _ := strconv.ParseFloat(r.lat[2:], 64)
+	latdeg, _ := strconv.ParseInt(lat[0:2], 10, 32)
+	latmin, _ := strconv.ParseFloat(lat[2:], 64)
 	latv := float64(latdeg) + (latmin / 60.0)
-	if r.ns == "S" {
+	if ns == "S" {
 		latv = -latv
 	}
 	return latv
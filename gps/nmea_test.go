@@ -0,0 +1,166 @@
+package gps
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// dumpNMEA describes every message read from a log.
+func dumpNMEA(t *testing.T, log []byte) []byte {
+	var b bytes.Buffer
+	g := newGPS(&rc{bytes.NewReader(log)}, Config{Checksum: ChecksumPass})
+	for msg := range g.NMEA() {
+		fmt.Fprintf(&b, "%q %v %T %+v\n", msg.Line(), msg.ChecksumOK(), msg.Msg(), msg.Msg())
+	}
+	fmt.Fprintf(&b, "%+v\n", g.Stats())
+	return b.Bytes()
+}
+
+// TestNMEACorpus checks the parse of a log mixing u-blox and MTK output
+// with the damage of real captures.
+func TestNMEACorpus(t *testing.T) {
+	log, err := ioutil.ReadFile("testdata/nmea.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := dumpNMEA(t, log)
+	if *update {
+		if err := ioutil.WriteFile("testdata/nmea.golden", got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile("testdata/nmea.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gl, wl := bytes.Split(got, []byte("\n")), bytes.Split(want, []byte("\n"))
+	for i := 0; i < len(gl) && i < len(wl); i++ {
+		if !bytes.Equal(gl[i], wl[i]) {
+			t.Fatalf("line %d: wanted\n%s\ngot\n%s", i+1, wl[i], gl[i])
+		}
+	}
+	if len(gl) != len(wl) {
+		t.Fatalf("wanted %d lines, got %d", len(wl), len(gl))
+	}
+}
+
+// TestNMEAMalformedFields checks that fields too short to slice or too
+// wide for an int are not decoded.
+func TestNMEAMalformedFields(t *testing.T) {
+	tts := []string{
+		"GPRMC,1,A,4807.038,N,01131.000,E,022.4,084.4,230318,003.1,W",
+		"GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,2303,003.1,W",
+		"GPRMC,123519.,A,4807.038,N,01131.000,E,022.4,084.4,230318,003.1,W",
+		"GPGGA,123519,4807.038,N,01131.000,E,1,99999999999,0.9,545.4,M,46.9,M,,",
+		"GPGSV,99999999999,1,08,01,40,083,46",
+		"GPGSV,1,1,08,01,40,083,99999999999",
+		"GPGSA,A,3,99999999999,,,,,,,,,,,,2.5,1.3,2.1",
+	}
+	for i, tt := range tts {
+		line := bytes.TrimSpace(nmeaSentence(tt))
+		msg, ok := parseNMEA(line)
+		if !ok {
+			continue
+		}
+		if _, unk := msg.Msg().(*nmeaUnk); !unk {
+			t.Errorf("#%d: expected unknown message, got %T", i, msg.Msg())
+		}
+		if !msg.Fix().IsZero() {
+			t.Errorf("#%d: expected zero fix, got %v", i, msg.Fix())
+		}
+	}
+}
+
+// TestNMEAAccessorsMalformed checks that accessors on fields the parser
+// did not shape give zero values instead of panicking.
+func TestNMEAAccessorsMalformed(t *testing.T) {
+	if fix := (&RMC{fix: "1", date: "2303"}).Fix(); !fix.IsZero() {
+		t.Errorf("expected zero fix, got %v", fix)
+	}
+	if n := (&GGA{sats: "99999999999"}).Satellites(); n != 0 {
+		t.Errorf("expected 0 satellites, got %d", n)
+	}
+	if n := (&GSV{total: "99999999999"}).Sentences(); n != 0 {
+		t.Errorf("expected 0 sentences, got %d", n)
+	}
+	if prns := (&GSA{prns: []string{"99999999999"}}).PRNs(); prns[0] != 0 {
+		t.Errorf("expected PRN 0, got %d", prns[0])
+	}
+	if v := parseFloat64("1.2.3"); v == v {
+		t.Errorf("expected NaN, got %g", v)
+	}
+}
+
+// TestParseNMEAAllocs holds the parser to its copy of the line and its
+// message.
+func TestParseNMEAAllocs(t *testing.T) {
+	tts := []struct {
+		line   string
+		allocs float64
+	}{
+		{"$GNRMC,025503.00,A,4807.03812,N,01131.00021,E,9.338,84.42,120318,,,A*7C", 2},
+		{"$GNGGA,025503.00,4807.03812,N,01131.00021,E,1,12,0.91,545.4,M,47.0,M,,*73", 2},
+		{"$GPVTG,054.7,T,034.4,M,005.5,N,010.2,K,A*25", 2},
+		{"$GPGSV,3,1,10,02,40,083,46,05,17,308,41,12,07,344,39,14,22,228,45,1*6F", 3},
+		{"$GPGSA,A,3,04,05,,09,12,,,24,,,,,2.5,1.3,2.1*39", 3},
+		{"$GNTXT,01,01,02,u-blox AG - www.u-blox.com*4E", 1},
+	}
+	for i, tt := range tts {
+		buf := []byte(tt.line)
+		allocs := testing.AllocsPerRun(100, func() {
+			if _, ok := parseNMEA(buf); !ok {
+				t.Fatalf("#%d: failed to parse %q", i, tt.line)
+			}
+		})
+		if allocs > tt.allocs {
+			t.Errorf("#%d: wanted at most %g allocs, got %g", i, tt.allocs, allocs)
+		}
+	}
+}
+
+func benchmarkNMEA(b *testing.B, line string) {
+	buf := []byte(line)
+	b.SetBytes(int64(len(buf)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, ok := parseNMEA(buf); !ok {
+			b.Fatalf("failed to parse %q", line)
+		}
+	}
+}
+
+func BenchmarkRMC(b *testing.B) {
+	benchmarkNMEA(b, "$GNRMC,025503.00,A,4807.03812,N,01131.00021,E,9.338,84.42,120318,,,A*7C")
+}
+
+func BenchmarkGGA(b *testing.B) {
+	benchmarkNMEA(b, "$GNGGA,025503.00,4807.03812,N,01131.00021,E,1,12,0.91,545.4,M,47.0,M,,*73")
+}
+
+func BenchmarkGSV(b *testing.B) {
+	benchmarkNMEA(b, "$GPGSV,3,1,10,02,40,083,46,05,17,308,41,12,07,344,39,14,22,228,45,1*6F")
+}
+
+func BenchmarkUnknown(b *testing.B) {
+	benchmarkNMEA(b, "$GNTXT,01,01,02,u-blox AG - www.u-blox.com*4E")
+}
+
+// BenchmarkLog reads a log as ingest does.
+func BenchmarkLog(b *testing.B) {
+	log, err := ioutil.ReadFile("testdata/nmea.log")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(log)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		g := newGPS(&rc{bytes.NewReader(log)}, Config{Checksum: ChecksumPass})
+		for range g.NMEA() {
+		}
+	}
+}
@@ -0,0 +1,477 @@
+package gps
+
+import "strings"
+
+// Sentence formats adapted from http://www.gpsinformation.org/dale/nmea.htm
+
+// nmeaTalkers are the accepted talker ids; "P" starts proprietary
+// sentences.
+var nmeaTalkers = []string{
+	"GP", // GPS
+	"GL", // GLONASS
+	"GA", // Galileo
+	"BD", // BeiDou
+	"GN", // Generic GNSS
+	"P",  // Proprietary
+}
+
+// parseNMEA parses a sentence without its line ending. Returns false
+// if the sentence is malformed.
+//
+// Fields point into a single copy of the line so a sentence costs
+// two allocations: its text and its message. The copy cannot be left
+// out; line is the reader's buffer, reused for the next sentence while
+// consumers still hold this one. GSV and GSA sentences cost a third
+// for their satellites.
+func parseNMEA(line []byte) (NMEA, bool) {
+	p := nmeaParser{b: line}
+	if !p.talker() {
+		return NMEA{}, false
+	}
+	msg := p.sentence()
+	if msg == nil {
+		// Sentences that do not decode are passed through.
+		msg = &nmeaUnk{}
+	} else if p.pos != len(line) {
+		// Trailing characters after the checksum.
+		return NMEA{}, false
+	}
+	return NMEA{line: p.line(), NMEAi: msg}, true
+}
+
+// nmeaParser reads the comma separated fields of a sentence.
+type nmeaParser struct {
+	b   []byte
+	s   string
+	pos int
+}
+
+// line is the sentence as a string, newline terminated.
+func (p *nmeaParser) line() string {
+	if p.s == "" {
+		var sb strings.Builder
+		sb.Grow(len(p.b) + 1)
+		sb.Write(p.b)
+		sb.WriteByte('\n')
+		p.s = sb.String()
+	}
+	return p.s
+}
+
+func (p *nmeaParser) text(lo, hi int) string { return p.line()[lo:hi] }
+
+func (p *nmeaParser) talker() bool {
+	if len(p.b) == 0 || p.b[0] != '$' {
+		return false
+	}
+	p.pos = 1
+	for _, t := range nmeaTalkers {
+		if p.literal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *nmeaParser) literal(s string) bool {
+	if len(p.b)-p.pos < len(s) || string(p.b[p.pos:p.pos+len(s)]) != s {
+		return false
+	}
+	p.pos += len(s)
+	return true
+}
+
+// field reads the next field; false if the sentence has no more fields.
+func (p *nmeaParser) field() (lo, hi int, ok bool) {
+	if p.pos >= len(p.b) || p.b[p.pos] != ',' {
+		return 0, 0, false
+	}
+	lo, hi = p.pos+1, p.pos+1
+	for hi < len(p.b) && p.b[hi] != ',' && p.b[hi] != '*' {
+		hi++
+	}
+	p.pos = hi
+	return lo, hi, true
+}
+
+// req reads a field that must be set.
+func (p *nmeaParser) req(dst *string, class nmeaClass) bool {
+	lo, hi, ok := p.field()
+	if !ok || !class(p.b[lo:hi]) {
+		return false
+	}
+	*dst = p.text(lo, hi)
+	return true
+}
+
+// opt reads a field that may be empty.
+func (p *nmeaParser) opt(dst *string, class nmeaClass) bool {
+	lo, hi, ok := p.field()
+	if !ok || (lo != hi && !class(p.b[lo:hi])) {
+		return false
+	}
+	if lo != hi {
+		*dst = p.text(lo, hi)
+	}
+	return true
+}
+
+// unit reads a field that is empty or the unit of the previous field.
+func (p *nmeaParser) unit(u byte) bool {
+	lo, hi, ok := p.field()
+	return ok && (lo == hi || (hi-lo == 1 && p.b[lo] == u))
+}
+
+// trailing reads a field that may be left out entirely, as for fields
+// added by later NMEA versions.
+func (p *nmeaParser) trailing(dst *string, class nmeaClass) {
+	pos := p.pos
+	if !p.req(dst, class) {
+		p.pos = pos
+	}
+}
+
+// chksum reads the checksum that ends every decoded sentence.
+func (p *nmeaParser) chksum(dst *string) bool {
+	if len(p.b)-p.pos < 3 || p.b[p.pos] != '*' ||
+		!nmeaHex(p.b[p.pos+1:p.pos+2]) || !nmeaHex(p.b[p.pos+2:p.pos+3]) {
+		return false
+	}
+	*dst = p.text(p.pos, p.pos+3)
+	p.pos += 3
+	return true
+}
+
+// sentence reads the decoded sentence types; nil for other sentences.
+func (p *nmeaParser) sentence() NMEAi {
+	switch {
+	case p.literal("RMC"):
+		return p.rmc()
+	case p.literal("GGA"):
+		return p.gga()
+	case p.literal("GSV"):
+		return p.gsv()
+	case p.literal("GSA"):
+		return p.gsa()
+	case p.literal("VTG"):
+		return p.vtg()
+	}
+	return nil
+}
+
+// RMC - NMEA has its own version of essential gps pvt (position,
+// velocity, time) data. It is called RMC, The Recommended Minimum,
+// which will look similar to:
+//
+//	$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230318,003.1,W*6A
+//
+// Where:
+//
+//	RMC          Recommended Minimum sentence C
+//	123519       Fix taken at 12:35:19 UTC
+//	A            Status A=active or V=Void.
+//	4807.038,N   Latitude 48 deg 07.038' N
+//	01131.000,E  Longitude 11 deg 31.000' E
+//	022.4        Speed over the ground in knots
+//	084.4        Track angle in degrees True
+//	230318       Date - 23rd of March 2018
+//	003.1,W      Magnetic Variation
+//	*6A          The checksum data, always begins with *
+func (p *nmeaParser) rmc() NMEAi {
+	r := &RMC{}
+	var mode string
+	if !(p.req(&r.fix, nmeaHHMMSS) &&
+		p.req(&r.status, nmeaStatus) &&
+		p.opt(&r.lat, nmeaFixed) &&
+		p.opt(&r.ns, nmeaNS) &&
+		p.opt(&r.lon, nmeaFixed) &&
+		p.opt(&r.we, nmeaWE) &&
+		p.opt(&r.knots, nmeaFixed) &&
+		p.opt(&r.track, nmeaFixed) &&
+		p.req(&r.date, nmeaDDMMYY) &&
+		p.opt(&r.magvar, nmeaFixed) &&
+		p.opt(&r.magvarwe, nmeaNSWE)) {
+		return nil
+	}
+	// NMEA 2.3 mode; don't know why this appears.
+	p.trailing(&mode, nmeaRMCMode)
+	if !p.chksum(&r.chksum) {
+		return nil
+	}
+	return r
+}
+
+// GGA - essential fix data which provide 3D location and accuracy data.
+//
+//	$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47
+//
+// Where:
+//
+//	GGA          Global Positioning System Fix Data
+//	123519       Fix taken at 12:35:19 UTC
+//	4807.038,N   Latitude 48 deg 07.038' N
+//	01131.000,E  Longitude 11 deg 31.000' E
+//	1            Fix quality: 0 = invalid
+//	                          1 = GPS fix (SPS)
+//	                          2 = DGPS fix
+//	                          3 = PPS fix
+//	                          4 = Real Time Kinematic
+//	                          5 = Float RTK
+//	                          6 = estimated (dead reckoning)
+//	                          7 = Manual input mode
+//	                          8 = Simulation mode
+//	08           Number of satellites being tracked
+//	0.9          Horizontal dilution of position
+//	545.4,M      Altitude, Meters, above mean sea level
+//	46.9,M       Height of geoid (mean sea level) above WGS84 ellipsoid
+//	(empty)      Time in seconds since last DGPS update
+//	(empty)      DGPS station ID number
+//	*47          The checksum data, always begins with *
+func (p *nmeaParser) gga() NMEAi {
+	g := &GGA{}
+	if !(p.opt(&g.fix, nmeaHHMMSS) &&
+		p.opt(&g.lat, nmeaFixed) &&
+		p.opt(&g.ns, nmeaNS) &&
+		p.opt(&g.lon, nmeaFixed) &&
+		p.opt(&g.we, nmeaWE) &&
+		p.opt(&g.quality, nmeaQuality) &&
+		p.opt(&g.sats, nmeaCount) &&
+		p.opt(&g.hdop, nmeaDecimal) &&
+		p.opt(&g.alt, nmeaSigned) &&
+		p.unit('M') &&
+		p.opt(&g.geoidsep, nmeaSigned) &&
+		p.unit('M') &&
+		p.opt(&g.dgpsAge, nmeaDecimal) &&
+		p.opt(&g.dgpsStation, nmeaStation) &&
+		p.chksum(&g.chksum)) {
+		return nil
+	}
+	return g
+}
+
+// GSV - satellites in view; one sentence holds up to four satellites so
+// a full view may span several sentences.
+//
+//	$GPGSV,2,1,08,01,40,083,46,02,17,308,41,12,07,344,39,14,22,228,45*75
+//
+// Where:
+//
+//	GSV          Satellites in view
+//	2            Number of sentences for full data
+//	1            Sentence 1 of 2
+//	08           Number of satellites in view
+//	01           Satellite PRN number
+//	40           Elevation, degrees
+//	083          Azimuth, degrees
+//	46           SNR - higher is better; empty when not tracking
+//	             for up to 4 satellites per sentence
+//	*75          The checksum data, always begins with *
+func (p *nmeaParser) gsv() NMEAi {
+	g := &GSV{}
+	if !(p.req(&g.total, nmeaCount) &&
+		p.req(&g.num, nmeaCount) &&
+		p.req(&g.inview, nmeaCount)) {
+		return nil
+	}
+	for {
+		pos := p.pos
+		var s gsvSat
+		if !(p.req(&s.prn, nmeaCount) &&
+			p.opt(&s.elev, nmeaCount) &&
+			p.opt(&s.az, nmeaCount) &&
+			p.opt(&s.snr, nmeaCount)) {
+			p.pos = pos
+			break
+		}
+		if g.sats == nil {
+			g.sats = make([]gsvSat, 0, 4)
+		}
+		g.sats = append(g.sats, s)
+	}
+	// NMEA 4.1 signal id.
+	p.trailing(&g.signal, nmeaSignal)
+	if !p.chksum(&g.chksum) {
+		return nil
+	}
+	return g
+}
+
+// GSA - dilution of precision and the satellites used in the fix.
+//
+//	$GPGSA,A,3,04,05,,09,12,,,24,,,,,2.5,1.3,2.1*39
+//
+// Where:
+//
+//	GSA      Satellite status
+//	A        Auto selection of 2D or 3D fix (M = manual)
+//	3        3D fix - values include: 1 = no fix
+//	                                  2 = 2D fix
+//	                                  3 = 3D fix
+//	04,05... PRNs of satellites used for fix (space for 12)
+//	2.5      PDOP (dilution of precision)
+//	1.3      Horizontal dilution of precision (HDOP)
+//	2.1      Vertical dilution of precision (VDOP)
+//	*39      The checksum data, always begins with *
+func (p *nmeaParser) gsa() NMEAi {
+	g := &GSA{}
+	if !(p.req(&g.mode, nmeaMode) && p.req(&g.fixMode, nmeaFixMode)) {
+		return nil
+	}
+	for i := 0; i < 12; i++ {
+		var prn string
+		if !p.opt(&prn, nmeaCount) {
+			return nil
+		}
+		if prn != "" {
+			if g.prns == nil {
+				g.prns = make([]string, 0, 12)
+			}
+			g.prns = append(g.prns, prn)
+		}
+	}
+	if !(p.opt(&g.pdop, nmeaDecimal) &&
+		p.opt(&g.hdop, nmeaDecimal) &&
+		p.opt(&g.vdop, nmeaDecimal)) {
+		return nil
+	}
+	// NMEA 4.1 GNSS system id.
+	p.trailing(&g.system, nmeaSignal)
+	if !p.chksum(&g.chksum) {
+		return nil
+	}
+	return g
+}
+
+// VTG - track made good and ground speed.
+//
+//	$GPVTG,054.7,T,034.4,M,005.5,N,010.2,K,A*25
+//
+// Where:
+//
+//	VTG          Track made good and ground speed
+//	054.7,T      True track made good (degrees)
+//	034.4,M      Magnetic track made good
+//	005.5,N      Ground speed, knots
+//	010.2,K      Ground speed, kilometers per hour
+//	A            Mode: A=autonomous, D=differential, E=estimated, N=invalid
+//	*25          The checksum data, always begins with *
+func (p *nmeaParser) vtg() NMEAi {
+	v := &VTG{}
+	if !(p.opt(&v.track, nmeaFixed) &&
+		p.unit('T') &&
+		p.opt(&v.magtrack, nmeaFixed) &&
+		p.unit('M') &&
+		p.opt(&v.knots, nmeaFixed) &&
+		p.unit('N') &&
+		p.opt(&v.kmh, nmeaFixed) &&
+		p.unit('K')) {
+		return nil
+	}
+	// NMEA 2.3 mode.
+	p.trailing(&v.mode, nmeaFAA)
+	if !p.chksum(&v.chksum) {
+		return nil
+	}
+	return v
+}
+
+// nmeaClass matches the text of a field.
+type nmeaClass func(b []byte) bool
+
+var (
+	nmeaStatus  = nmeaOneOf("AV")
+	nmeaNS      = nmeaOneOf("NS")
+	nmeaWE      = nmeaOneOf("WE")
+	nmeaNSWE    = nmeaOneOf("NSWE")
+	nmeaRMCMode = nmeaOneOf("ADN")
+	nmeaQuality = nmeaOneOf("012345678")
+	nmeaMode    = nmeaOneOf("AM")
+	nmeaFixMode = nmeaOneOf("123")
+	nmeaFAA     = nmeaOneOf("ADEN")
+	// nmeaCount covers satellite counts, PRNs, elevations, azimuths,
+	// and SNRs.
+	nmeaCount   = nmeaUint(3)
+	nmeaStation = nmeaUint(4)
+)
+
+// nmeaOneOf matches a single character of a set.
+func nmeaOneOf(set string) nmeaClass {
+	return func(b []byte) bool {
+		if len(b) != 1 {
+			return false
+		}
+		for i := 0; i < len(set); i++ {
+			if set[i] == b[0] {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// nmeaDigits matches [0-9]+.
+func nmeaDigits(b []byte) bool {
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return len(b) > 0
+}
+
+// nmeaUint matches up to n digits, so the field always fits an int.
+func nmeaUint(n int) nmeaClass {
+	return func(b []byte) bool { return len(b) <= n && nmeaDigits(b) }
+}
+
+// nmeaHHMMSS matches hhmmss('.'[0-9]+)?.
+func nmeaHHMMSS(b []byte) bool {
+	if len(b) < 6 || !nmeaDigits(b[:6]) {
+		return false
+	}
+	return len(b) == 6 || (b[6] == '.' && nmeaDigits(b[7:]))
+}
+
+// nmeaDDMMYY matches ddmmyy.
+func nmeaDDMMYY(b []byte) bool { return len(b) == 6 && nmeaDigits(b) }
+
+// nmeaDecimal matches [0-9]+('.'[0-9]+)?.
+func nmeaDecimal(b []byte) bool {
+	for i, c := range b {
+		if c == '.' {
+			return nmeaDigits(b[:i]) && nmeaDigits(b[i+1:])
+		}
+	}
+	return nmeaDigits(b)
+}
+
+// nmeaFixed matches [0-9]+'.'[0-9]+.
+func nmeaFixed(b []byte) bool {
+	for i, c := range b {
+		if c == '.' {
+			return nmeaDigits(b[:i]) && nmeaDigits(b[i+1:])
+		}
+	}
+	return false
+}
+
+// nmeaSigned matches '-'?[0-9]+('.'[0-9]+)?.
+func nmeaSigned(b []byte) bool {
+	if len(b) > 0 && b[0] == '-' {
+		b = b[1:]
+	}
+	return nmeaDecimal(b)
+}
+
+// nmeaHex matches [0-9A-F]+.
+func nmeaHex(b []byte) bool {
+	for _, c := range b {
+		if (c < '0' || c > '9') && (c < 'A' || c > 'F') {
+			return false
+		}
+	}
+	return len(b) > 0
+}
+
+// nmeaSignal matches an NMEA 4.1 signal or system id, [0-9A-F].
+func nmeaSignal(b []byte) bool { return len(b) == 1 && nmeaHex(b) }
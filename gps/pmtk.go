@@ -0,0 +1,83 @@
+package gps
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pmtkSentences are the PMTK314 output fields by sentence.
+var pmtkSentences = map[string]int{
+	"GLL": 0,
+	"RMC": 1,
+	"VTG": 2,
+	"GGA": 3,
+	"GSA": 4,
+	"GSV": 5,
+	"ZDA": 17,
+}
+
+// pmtkConstellations are the PMTK353 search mode fields by constellation.
+var pmtkConstellations = map[Constellation]int{
+	ConstellationGPS:     0,
+	ConstellationGLONASS: 1,
+	ConstellationGalileo: 2,
+	ConstellationBeiDou:  4,
+}
+
+func pmtkCommands(rc ReceiverConfig) (cmds []receiverCmd, err error) {
+	if rc.Rate != 0 {
+		// PMTK220: position fix interval in milliseconds.
+		ms := fixRatePeriods[rc.Rate].Nanoseconds() / 1e6
+		cmds = append(cmds, pmtkCmd(220, strconv.FormatInt(ms, 10)))
+	}
+	if rc.Sentences != nil {
+		// PMTK314: output rate per fix of each sentence.
+		fields := make([]string, 19)
+		for i := range fields {
+			fields[i] = "0"
+		}
+		for _, s := range rc.Sentences {
+			i, ok := pmtkSentences[s]
+			if !ok {
+				return nil, fmt.Errorf("gps: pmtk cannot select sentence %q", s)
+			}
+			fields[i] = "1"
+		}
+		cmds = append(cmds, pmtkCmd(314, strings.Join(fields, ",")))
+	}
+	if rc.Constellations != nil {
+		// PMTK353: GPS, GLONASS, Galileo, Galileo full, BeiDou.
+		fields := []string{"0", "0", "0", "0", "0"}
+		for _, c := range rc.Constellations {
+			i, ok := pmtkConstellations[c]
+			if !ok {
+				return nil, fmt.Errorf("gps: pmtk cannot select constellation %q", c)
+			}
+			fields[i] = "1"
+		}
+		cmds = append(cmds, pmtkCmd(353, strings.Join(fields, ",")))
+	}
+	return cmds, nil
+}
+
+// pmtkCmd is a PMTK command acknowledged by PMTK001.
+func pmtkCmd(typ int, args string) receiverCmd {
+	name := fmt.Sprintf("PMTK%03d", typ)
+	return receiverCmd{
+		name: name,
+		data: nmeaSentence(name + "," + args),
+		ack: func(n NMEA) (bool, bool) {
+			// $PMTK001,<cmd>,<flag>*<checksum>; flag 3 is success.
+			line := n.Line()
+			if i := strings.IndexByte(line, '*'); i > 0 {
+				line = line[:i]
+			}
+			f := strings.Split(line, ",")
+			if len(f) != 3 || f[0] != "$PMTK001" || f[1] != strconv.Itoa(typ) {
+				return false, false
+			}
+			return true, f[2] == "3"
+		},
+	}
+}
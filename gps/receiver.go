@@ -0,0 +1,158 @@
+package gps
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// Protocol is a receiver configuration protocol.
+type Protocol string
+
+const (
+	// ProtocolPMTK configures MediaTek receivers with PMTK sentences.
+	ProtocolPMTK Protocol = "pmtk"
+	// ProtocolUBX configures u-blox receivers with UBX-CFG messages.
+	ProtocolUBX Protocol = "ubx"
+)
+
+// Constellation is a GNSS constellation.
+type Constellation string
+
+const (
+	ConstellationGPS     Constellation = "gps"
+	ConstellationGLONASS Constellation = "glonass"
+	ConstellationGalileo Constellation = "galileo"
+	ConstellationBeiDou  Constellation = "beidou"
+)
+
+// ReceiverConfig is a receiver configuration profile. Unset fields
+// leave the receiver's settings as they are.
+type ReceiverConfig struct {
+	Protocol Protocol `json:"protocol"`
+	// Rate is the fix rate in Hz: 1, 5, or 10. Higher rates need a baud
+	// rate with room for the selected sentences.
+	Rate int `json:"rate,omitempty"`
+	// Constellations are enabled; the rest are disabled.
+	Constellations []Constellation `json:"constellations,omitempty"`
+	// Sentences (e.g., "RMC", "GSV") are emitted; the rest are not.
+	Sentences []string `json:"sentences,omitempty"`
+}
+
+// receiverCmd is a configuration command and its acknowledgment.
+type receiverCmd struct {
+	name string
+	data []byte
+	// ack reports whether a message acknowledges the command and
+	// whether the receiver accepted it.
+	ack func(NMEA) (acked, ok bool)
+}
+
+// receiverBackends build the commands for a profile by protocol.
+var receiverBackends = map[Protocol]func(ReceiverConfig) ([]receiverCmd, error){
+	ProtocolPMTK: pmtkCommands,
+	ProtocolUBX:  ubxCommands,
+}
+
+// ackTimeout is how long to wait for a command to be acknowledged.
+var ackTimeout = 2 * time.Second
+
+// fixRatePeriods maps supported fix rates to measurement periods.
+var fixRatePeriods = map[int]time.Duration{
+	1:  time.Second,
+	5:  200 * time.Millisecond,
+	10: 100 * time.Millisecond,
+}
+
+func (rc *ReceiverConfig) commands() ([]receiverCmd, error) {
+	backend, ok := receiverBackends[rc.Protocol]
+	if !ok {
+		return nil, fmt.Errorf("gps: unknown receiver protocol %q", rc.Protocol)
+	}
+	if _, ok := fixRatePeriods[rc.Rate]; !ok && rc.Rate != 0 {
+		return nil, fmt.Errorf("gps: unsupported fix rate %d Hz", rc.Rate)
+	}
+	return backend(*rc)
+}
+
+// ReadProfile reads a JSON receiver profile.
+func ReadProfile(p string) (rc ReceiverConfig, err error) {
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		return rc, err
+	}
+	if err = json.Unmarshal(b, &rc); err != nil {
+		return rc, fmt.Errorf("gps: %s: %v", p, err)
+	}
+	_, err = rc.commands()
+	return rc, err
+}
+
+// ConfigureDevice applies a profile to the receiver at a device path.
+func ConfigureDevice(p string, cfg Config, rc ReceiverConfig) error {
+	var f *os.File
+	var err error
+	if cfg.Baud == 0 {
+		f, err = openTTY(p)
+	} else {
+		f, _, err = openSerial(p, cfg.Baud)
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return Configure(f, rc)
+}
+
+// Configure sends a profile to a receiver and waits for each command to
+// be acknowledged. Waits time out if rw has read deadlines.
+func Configure(rw io.ReadWriter, rc ReceiverConfig) error {
+	cmds, err := rc.commands()
+	if err != nil {
+		return err
+	}
+	dl, hasDeadline := rw.(interface {
+		SetReadDeadline(time.Time) error
+	})
+	if hasDeadline {
+		defer dl.SetReadDeadline(time.Time{})
+	}
+	// Drop bad checksums; messages are only read for acknowledgments.
+	g := &GPS{}
+	r := bufio.NewReaderSize(rw, maxLineLen)
+	for _, cmd := range cmds {
+		if hasDeadline {
+			if err := dl.SetReadDeadline(time.Now().Add(ackTimeout)); err != nil {
+				return err
+			}
+		}
+		if _, err := rw.Write(cmd.data); err != nil {
+			return err
+		}
+		if err := g.waitAck(r, cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *GPS) waitAck(r *bufio.Reader, cmd receiverCmd) error {
+	for {
+		msg, err := g.next(r)
+		if os.IsTimeout(err) {
+			return fmt.Errorf("gps: no acknowledgment for %s", cmd.name)
+		} else if err != nil {
+			return err
+		}
+		if acked, ok := cmd.ack(msg); acked {
+			if !ok {
+				return fmt.Errorf("gps: receiver rejected %s", cmd.name)
+			}
+			return nil
+		}
+	}
+}
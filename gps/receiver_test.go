@@ -0,0 +1,185 @@
+package gps
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pipeDev reads replies from a fake receiver and writes it commands.
+type pipeDev struct {
+	*os.File
+	w *os.File
+}
+
+func (p *pipeDev) Write(b []byte) (int, error) { return p.w.Write(b) }
+
+// fakeReceiver answers each command with the output of reply. Returns
+// the commands received once the device is closed.
+func fakeReceiver(t *testing.T, reply func(cmd []byte) []byte) (*pipeDev, func() []string) {
+	hr, dw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dr, hw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmdc := make(chan []string, 1)
+	go func() {
+		defer dw.Close()
+		var cmds []string
+		r := bufio.NewReader(dr)
+		for {
+			cmd, err := readCmd(r)
+			if err != nil {
+				cmdc <- cmds
+				return
+			}
+			cmds = append(cmds, string(cmd))
+			dw.Write(reply(cmd))
+		}
+	}()
+	dev := &pipeDev{hr, hw}
+	return dev, func() []string {
+		hw.Close()
+		hr.Close()
+		return <-cmdc
+	}
+}
+
+func readCmd(r *bufio.Reader) ([]byte, error) {
+	c, err := r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	if c[0] == '$' {
+		return r.ReadBytes('\n')
+	}
+	hdr := make([]byte, ubxHeaderLen)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, err
+	}
+	frame := make([]byte, ubxHeaderLen+int(binary.LittleEndian.Uint16(hdr[4:]))+2)
+	copy(frame, hdr)
+	_, err = io.ReadFull(r, frame[ubxHeaderLen:])
+	return frame, err
+}
+
+func pmtkAck(cmd []byte) []byte {
+	// Bury the acknowledgment in the sentence stream.
+	return append([]byte("$GPRMC,043019.00,V,,,,,,,120318,,,N*7B\r\n"),
+		nmeaSentence("PMTK001,"+string(cmd[5:8])+",3")...)
+}
+
+func ubxAck(cmd []byte) []byte {
+	return ubxFrame(ubxClassACK, ubxAckACK, cmd[2:4])
+}
+
+func TestConfigurePMTK(t *testing.T) {
+	dev, done := fakeReceiver(t, pmtkAck)
+	rc := ReceiverConfig{
+		Protocol:       ProtocolPMTK,
+		Rate:           5,
+		Constellations: []Constellation{ConstellationGPS, ConstellationGLONASS},
+		Sentences:      []string{"RMC", "GGA"},
+	}
+	if err := Configure(dev, rc); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"$PMTK220,200*2C\r\n",
+		"$PMTK314,0,1,0,1,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0*28\r\n",
+		"$PMTK353,1,1,0,0,0*2B\r\n",
+	}
+	cmds := done()
+	if strings.Join(cmds, "") != strings.Join(want, "") {
+		t.Errorf("wanted %q, got %q", want, cmds)
+	}
+}
+
+func TestConfigureUBX(t *testing.T) {
+	dev, done := fakeReceiver(t, ubxAck)
+	rc := ReceiverConfig{
+		Protocol:       ProtocolUBX,
+		Rate:           10,
+		Constellations: []Constellation{ConstellationGPS, ConstellationGalileo},
+		Sentences:      []string{"RMC"},
+	}
+	if err := Configure(dev, rc); err != nil {
+		t.Fatal(err)
+	}
+	cmds := done()
+	if len(cmds) != 1+len(ubxSentences)+1 {
+		t.Fatalf("expected rate, sentence, and gnss commands, got %d", len(cmds))
+	}
+	rate := []byte(cmds[0])
+	if !bytes.Equal(rate, ubxFrame(ubxClassCFG, ubxCfgRATE, []byte{100, 0, 1, 0, 1, 0})) {
+		t.Errorf("bad CFG-RATE %x", rate)
+	}
+	for _, cmd := range cmds[1 : len(cmds)-1] {
+		id, enabled := cmd[7], cmd[8] == 1
+		if enabled != (id == 0x04) {
+			t.Errorf("expected only RMC enabled, got %x", cmd)
+		}
+	}
+	gnss := []byte(cmds[len(cmds)-1])
+	if gnss[3] != ubxCfgGNSS || gnss[ubxHeaderLen+3] != 4 {
+		t.Fatalf("bad CFG-GNSS %x", gnss)
+	}
+	for i := 0; i < 4; i++ {
+		blk := gnss[ubxHeaderLen+4+8*i:]
+		enabled := blk[4]&1 == 1
+		if enabled != (blk[0] == 0 || blk[0] == 2) {
+			t.Errorf("expected only GPS and Galileo enabled, got %x", blk[:8])
+		}
+	}
+}
+
+func TestConfigureRejected(t *testing.T) {
+	dev, done := fakeReceiver(t, func(cmd []byte) []byte {
+		if cmd[3] == ubxCfgGNSS {
+			return ubxFrame(ubxClassACK, 0x00, cmd[2:4])
+		}
+		return ubxAck(cmd)
+	})
+	defer done()
+	rc := ReceiverConfig{
+		Protocol:       ProtocolUBX,
+		Rate:           1,
+		Constellations: []Constellation{ConstellationBeiDou},
+	}
+	if err := Configure(dev, rc); err == nil || !strings.Contains(err.Error(), "CFG-GNSS") {
+		t.Errorf("expected CFG-GNSS rejected, got %v", err)
+	}
+}
+
+func TestConfigureTimeout(t *testing.T) {
+	defer func(d time.Duration) { ackTimeout = d }(ackTimeout)
+	ackTimeout = 50 * time.Millisecond
+	dev, done := fakeReceiver(t, func(cmd []byte) []byte { return nil })
+	defer done()
+	if err := Configure(dev, ReceiverConfig{Protocol: ProtocolPMTK, Rate: 10}); err == nil {
+		t.Errorf("expected timeout without acknowledgment")
+	}
+}
+
+func TestReceiverConfigInvalid(t *testing.T) {
+	tests := []ReceiverConfig{
+		{Protocol: "sirf"},
+		{Protocol: ProtocolPMTK, Rate: 2},
+		{Protocol: ProtocolPMTK, Sentences: []string{"XYZ"}},
+		{Protocol: ProtocolUBX, Sentences: []string{"RMC", "XYZ"}},
+		{Protocol: ProtocolUBX, Constellations: []Constellation{"navic"}},
+	}
+	for _, rc := range tests {
+		if _, err := rc.commands(); err == nil {
+			t.Errorf("expected error for %+v", rc)
+		}
+	}
+}
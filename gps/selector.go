@@ -0,0 +1,200 @@
+package gps
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultStale is how long a source keeps its fix without news.
+	defaultStale = 3 * time.Second
+	// A source must beat the selected one by this many satellites, or
+	// as many satellites and this much HDOP, to take over.
+	satMargin  = 2
+	hdopMargin = 0.5
+)
+
+// SelectorConfig configures a Selector. Zero values take defaults.
+type SelectorConfig struct {
+	// Stale is how long a source keeps its fix after its last valid
+	// fix; defaults to 3 seconds.
+	Stale time.Duration
+	// OnSwitch, if set, is called when the selected source changes. To
+	// is empty if no source is left.
+	OnSwitch func(from, to string)
+}
+
+// Selector merges GPS receivers into one stream of the best source.
+// Sources are ranked by fix validity, then satellites, then HDOP.
+type Selector struct {
+	cfg SelectorConfig
+	ch  chan NMEA
+
+	mu      sync.Mutex
+	sources map[string]*source
+	cur     string
+
+	// chMu holds off Close while sending.
+	chMu   sync.RWMutex
+	closed bool
+}
+
+// source is the latest quality of a receiver.
+type source struct {
+	valid   bool
+	lastFix time.Time
+	sats    int
+	hdop    float64
+}
+
+// NewSelector makes a selector without sources.
+func NewSelector(cfg SelectorConfig) *Selector {
+	if cfg.Stale == 0 {
+		cfg.Stale = defaultStale
+	}
+	return &Selector{
+		cfg:     cfg,
+		ch:      make(chan NMEA),
+		sources: make(map[string]*source),
+	}
+}
+
+// NMEA streams the messages of the selected source. Closes on Close.
+func (s *Selector) NMEA() <-chan NMEA { return s.ch }
+
+// Source is the name of the selected source; empty if none.
+func (s *Selector) Source() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cur
+}
+
+// Update takes a message from a source, adding the source if it is
+// new. Messages of the selected source pass to the stream, blocking
+// until read.
+func (s *Selector) Update(name string, msg NMEA) {
+	now := msg.Received()
+	if now.IsZero() {
+		now = time.Now()
+	}
+	s.mu.Lock()
+	src, ok := s.sources[name]
+	if !ok {
+		src = &source{hdop: math.NaN()}
+		s.sources[name] = src
+	}
+	src.update(msg, now)
+	from := s.cur
+	s.cur = s.pick(now)
+	to := s.cur
+	s.mu.Unlock()
+	s.switched(from, to)
+	if to == name {
+		s.send(msg)
+	}
+}
+
+// Remove drops a source, such as an unplugged receiver.
+func (s *Selector) Remove(name string) {
+	s.mu.Lock()
+	delete(s.sources, name)
+	from := s.cur
+	if s.cur == name {
+		s.cur = ""
+		s.cur = s.pick(time.Now())
+	}
+	to := s.cur
+	s.mu.Unlock()
+	s.switched(from, to)
+}
+
+// Close ends the stream.
+func (s *Selector) Close() {
+	s.chMu.Lock()
+	defer s.chMu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.ch)
+	}
+}
+
+func (s *Selector) send(msg NMEA) {
+	s.chMu.RLock()
+	defer s.chMu.RUnlock()
+	if !s.closed {
+		s.ch <- msg
+	}
+}
+
+func (s *Selector) switched(from, to string) {
+	if from != to && s.cfg.OnSwitch != nil {
+		s.cfg.OnSwitch(from, to)
+	}
+}
+
+// pick is the best source, keeping the selected one unless another
+// beats it.
+func (s *Selector) pick(now time.Time) string {
+	names := make([]string, 0, len(s.sources))
+	for name := range s.sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	best := s.cur
+	if _, ok := s.sources[best]; !ok {
+		best = ""
+	}
+	for _, name := range names {
+		if best == "" || s.beats(s.sources[name], s.sources[best], now) {
+			best = name
+		}
+	}
+	return best
+}
+
+// beats is set if source a should take over from b.
+func (s *Selector) beats(a, b *source, now time.Time) bool {
+	af, bf := a.fresh(now, s.cfg.Stale), b.fresh(now, s.cfg.Stale)
+	if af != bf || !af {
+		return af && !bf
+	}
+	if a.sats >= b.sats+satMargin {
+		return true
+	}
+	// Unknown HDOP loses to any.
+	ah, bh := a.hdop, b.hdop
+	if math.IsNaN(ah) {
+		ah = math.Inf(1)
+	}
+	if math.IsNaN(bh) {
+		bh = math.Inf(1)
+	}
+	return a.sats >= b.sats && ah+hdopMargin < bh
+}
+
+// fresh is set if the source had a valid fix lately.
+func (src *source) fresh(now time.Time, stale time.Duration) bool {
+	return src.valid && now.Sub(src.lastFix) <= stale
+}
+
+func (src *source) update(msg NMEA, now time.Time) {
+	// Only messages with a fix time tell whether there is a fix; GGA
+	// and GSA report satellites and HDOP.
+	if !msg.Fix().IsZero() {
+		if src.valid = msg.Valid(); src.valid {
+			src.lastFix = now
+		}
+	}
+	m := msg.Msg()
+	if g, ok := m.(*GGA); ok && g.Quality() == FixInvalid {
+		src.valid = false
+	}
+	if n, ok := m.(interface{ Satellites() int }); ok {
+		src.sats = n.Satellites()
+	}
+	if h, ok := m.(interface{ HDOP() float64 }); ok && !math.IsNaN(h.HDOP()) {
+		src.hdop = h.HDOP()
+	}
+}
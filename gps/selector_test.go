@@ -0,0 +1,118 @@
+package gps
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// selectorMsgs are the RMC and GGA sentences of a receiver's fix.
+func selectorMsgs(t *testing.T, at time.Time, valid bool, sats int, hdop float64) []NMEA {
+	fix := Fix{
+		Time:       at.UTC(),
+		Valid:      valid,
+		Latitude:   48.1173,
+		Longitude:  11.5167,
+		Altitude:   math.NaN(),
+		Speed:      5,
+		Course:     90,
+		MagVar:     math.NaN(),
+		Satellites: sats,
+		HDOP:       hdop,
+	}
+	var ret []NMEA
+	for _, b := range [][]byte{Encoder{}.RMC(fix), Encoder{}.GGA(fix)} {
+		msg, ok := parseNMEA([]byte(strings.TrimSpace(string(b))))
+		if !ok {
+			t.Fatalf("could not parse %q", b)
+		}
+		msg.received = at
+		ret = append(ret, msg)
+	}
+	return ret
+}
+
+func TestSelector(t *testing.T) {
+	var switches []string
+	sel := NewSelector(SelectorConfig{OnSwitch: func(from, to string) {
+		switches = append(switches, from+">"+to)
+	}})
+	outc := make(chan int)
+	go func() {
+		n := 0
+		for range sel.NMEA() {
+			n++
+		}
+		outc <- n
+	}()
+	start := time.Date(2018, 3, 12, 2, 55, 3, 0, time.UTC)
+	tts := []struct {
+		src   string
+		sec   int
+		valid bool
+		sats  int
+		hdop  float64
+		want  string
+	}{
+		{"a", 0, true, 8, 1.0, "a"},
+		// Not enough better to take over.
+		{"b", 0, true, 9, 0.9, "a"},
+		{"b", 1, true, 10, 1.0, "b"},
+		{"a", 1, true, 8, 1.0, "b"},
+		// As many satellites and a much better HDOP.
+		{"a", 2, true, 10, 0.4, "a"},
+		// Lost fix.
+		{"a", 3, false, 3, math.NaN(), "b"},
+		{"b", 3, true, 10, 1.0, "b"},
+		{"a", 4, true, 12, 0.8, "a"},
+		// The selected receiver stops talking.
+		{"b", 8, true, 6, 1.5, "b"},
+	}
+	for i, tt := range tts {
+		for _, msg := range selectorMsgs(t, start.Add(time.Duration(tt.sec)*time.Second), tt.valid, tt.sats, tt.hdop) {
+			sel.Update(tt.src, msg)
+		}
+		if got := sel.Source(); got != tt.want {
+			t.Fatalf("#%d: expected %q selected, got %q", i, tt.want, got)
+		}
+	}
+	sel.Remove("a")
+	sel.Remove("b")
+	if got := sel.Source(); got != "" {
+		t.Errorf("expected no source, got %q", got)
+	}
+	sel.Close()
+	// Messages pass from the one that switches to a source; that is
+	// often the GGA, with the satellites that make it better.
+	if n := <-outc; n != 9 {
+		t.Errorf("expected 9 messages, got %d", n)
+	}
+	want := ">a a>b b>a a>b b>a a>b b>"
+	if got := strings.Join(switches, " "); got != want {
+		t.Errorf("expected switches %q, got %q", want, got)
+	}
+}
+
+func TestSelectorRemove(t *testing.T) {
+	sel := NewSelector(SelectorConfig{})
+	go func() {
+		for range sel.NMEA() {
+		}
+	}()
+	defer sel.Close()
+	start := time.Now()
+	for _, src := range []string{"a", "b"} {
+		for _, msg := range selectorMsgs(t, start, true, 8, 1.0) {
+			sel.Update(src, msg)
+		}
+	}
+	if sel.Source() != "a" {
+		t.Fatalf("expected first source, got %q", sel.Source())
+	}
+	// Unplugged.
+	sel.Remove("a")
+	if sel.Source() != "b" {
+		t.Errorf("expected failover to b, got %q", sel.Source())
+	}
+}
@@ -0,0 +1,52 @@
+package gps
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// AutoBaud probes for the baud rate of a serial GPS.
+const AutoBaud = -1
+
+// ErrNoBaud is returned when no probed baud rate yields valid NMEA.
+var ErrNoBaud = errors.New("gps: no baud rate with valid NMEA")
+
+// autoBaudRates are probed in order, most common receiver defaults first.
+var autoBaudRates = []int{9600, 4800, 38400, 115200, 57600, 19200, 230400, 460800}
+
+// probeTime is how long to listen for NMEA at each probed rate.
+var probeTime = 2 * time.Second
+
+// probeBaud tries each rate until a sentence with a valid checksum appears.
+func probeBaud(f *os.File, rates []int) (int, error) {
+	defer f.SetReadDeadline(time.Time{})
+	for _, baud := range rates {
+		if err := setSerial(f, baud); err != nil {
+			return 0, err
+		}
+		if err := f.SetReadDeadline(time.Now().Add(probeTime)); err != nil {
+			return 0, err
+		}
+		if hasNMEA(f) {
+			return baud, nil
+		}
+	}
+	return 0, ErrNoBaud
+}
+
+// hasNMEA reads until a valid sentence or an error such as a timeout.
+func hasNMEA(r io.Reader) bool {
+	br := bufio.NewReaderSize(r, maxLineLen)
+	for {
+		line, pfx, err := br.ReadLine()
+		if err != nil {
+			return false
+		}
+		if !pfx && checksumOK(line) {
+			return true
+		}
+	}
+}
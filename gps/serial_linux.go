@@ -0,0 +1,86 @@
+// +build linux
+
+package gps
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+var baudRates = map[int]uint32{
+	4800:   unix.B4800,
+	9600:   unix.B9600,
+	19200:  unix.B19200,
+	38400:  unix.B38400,
+	57600:  unix.B57600,
+	115200: unix.B115200,
+	230400: unix.B230400,
+	460800: unix.B460800,
+}
+
+// openTTY opens a tty for reading and writing without making it the
+// controlling terminal.
+func openTTY(p string) (*os.File, error) {
+	return os.OpenFile(p, os.O_RDWR|syscall.O_NOCTTY, 0)
+}
+
+// openSerial opens a tty in raw mode at a given baud rate, probing for
+// the rate if given AutoBaud. Returns the baud rate in use.
+func openSerial(p string, baud int) (*os.File, int, error) {
+	f, err := openTTY(p)
+	if err != nil {
+		return nil, 0, err
+	}
+	if baud == AutoBaud {
+		baud, err = probeBaud(f, autoBaudRates)
+	} else {
+		err = setSerial(f, baud)
+	}
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	return f, baud, nil
+}
+
+// setSerial puts a tty into raw 8N1 mode at a given baud rate.
+func setSerial(f *os.File, baud int) error {
+	speed, ok := baudRates[baud]
+	if !ok {
+		return fmt.Errorf("gps: unsupported baud rate %d", baud)
+	}
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var terr error
+	cerr := rc.Control(func(fd uintptr) { terr = setTermios(int(fd), speed) })
+	if cerr != nil {
+		return cerr
+	}
+	return terr
+}
+
+func setTermios(fd int, speed uint32) error {
+	t, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return err
+	}
+	// Same as cfmakeraw(3).
+	t.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP |
+		unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	t.Oflag &^= unix.OPOST
+	t.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	t.Cflag &^= unix.CSIZE | unix.PARENB | unix.CRTSCTS | unix.CBAUD
+	t.Cflag |= unix.CS8 | unix.CREAD | unix.CLOCAL | speed
+	t.Ispeed, t.Ospeed = speed, speed
+	t.Cc[unix.VMIN], t.Cc[unix.VTIME] = 1, 0
+	if err := unix.IoctlSetTermios(fd, unix.TCSETS, t); err != nil {
+		return err
+	}
+	// Drop anything received at the old rate.
+	return unix.IoctlSetInt(fd, unix.TCFLSH, unix.TCIFLUSH)
+}
@@ -0,0 +1,81 @@
+// +build linux
+
+package gps
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// openPty opens a pseudo-terminal and returns its master and slave path.
+func openPty(t *testing.T) (*os.File, string) {
+	m, err := os.OpenFile("/dev/ptmx", os.O_RDWR, 0)
+	if err != nil {
+		t.Skip(err)
+	}
+	n, err := unix.IoctlGetInt(int(m.Fd()), unix.TIOCGPTN)
+	if err != nil {
+		m.Close()
+		t.Skip(err)
+	}
+	unlock := int32(0)
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, m.Fd(), unix.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock)))
+	if errno != 0 {
+		m.Close()
+		t.Skip(errno)
+	}
+	return m, fmt.Sprintf("/dev/pts/%d", n)
+}
+
+// feedPty writes a sentence to the master until done is closed.
+func feedPty(m *os.File, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case <-time.After(50 * time.Millisecond):
+		}
+		m.Write([]byte("$GPRMC,043019.00,V,,,,,,,120318,,,N*7B\r\n"))
+	}
+}
+
+func TestSerial(t *testing.T) {
+	for _, baud := range []int{AutoBaud, 115200} {
+		m, slave := openPty(t)
+		done := make(chan struct{})
+		go feedPty(m, done)
+		g, err := NewGPSWithConfig(slave, Config{Baud: baud})
+		if err != nil {
+			close(done)
+			m.Close()
+			t.Fatal(err)
+		}
+		if baud == AutoBaud && g.Baud() != autoBaudRates[0] {
+			t.Errorf("expected probed baud %d, got %d", autoBaudRates[0], g.Baud())
+		} else if baud != AutoBaud && g.Baud() != baud {
+			t.Errorf("expected baud %d, got %d", baud, g.Baud())
+		}
+		msg, ok := <-g.NMEA()
+		if !ok {
+			t.Errorf("expected message, got %v", g.Close())
+		} else if msg.Line() != "$GPRMC,043019.00,V,,,,,,,120318,,,N*7B\n" {
+			t.Errorf("unexpected line %q", msg.Line())
+		}
+		close(done)
+		g.Close()
+		m.Close()
+	}
+}
+
+func TestSerialBadBaud(t *testing.T) {
+	m, slave := openPty(t)
+	defer m.Close()
+	if _, err := NewGPSWithConfig(slave, Config{Baud: 1234}); err == nil {
+		t.Fatal("expected error on unsupported baud rate")
+	}
+}
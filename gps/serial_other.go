@@ -0,0 +1,16 @@
+// +build !linux
+
+package gps
+
+import (
+	"errors"
+	"os"
+)
+
+var errNoSerial = errors.New("gps: serial configuration not supported")
+
+func openTTY(p string) (*os.File, error) { return os.OpenFile(p, os.O_RDWR, 0) }
+
+func openSerial(p string, baud int) (*os.File, int, error) { return nil, 0, errNoSerial }
+
+func setSerial(f *os.File, baud int) error { return errNoSerial }
@@ -0,0 +1,366 @@
+package gps
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bikeos/bosd/geo"
+)
+
+// RoutePoint is a point on a simulated route. Alt is NaN if unknown.
+type RoutePoint struct {
+	Lat float64
+	Lon float64
+	Alt float64
+}
+
+// SimSpeed sets the speed from a distance along the route onward.
+type SimSpeed struct {
+	// At is the distance from the start of the route in meters.
+	At float64
+	// Speed is in m/s.
+	Speed float64
+}
+
+// SimConfig configures a synthetic GPS.
+type SimConfig struct {
+	Route []RoutePoint
+	// Speeds is the speed profile; defaults to 5 m/s.
+	Speeds []SimSpeed
+	// Rate is the number of fixes per second; defaults to 1.
+	Rate int
+	// Noise is the standard deviation of position error in meters.
+	Noise float64
+	// Dropouts is the chance per second of losing the fix for
+	// DropoutTime.
+	Dropouts    float64
+	DropoutTime time.Duration
+	// ColdStart is the time before the first fix.
+	ColdStart time.Duration
+	// Realtime paces fixes by the clock instead of emitting them as
+	// fast as possible.
+	Realtime bool
+	// Loop restarts the route at its end instead of ending the stream.
+	Loop bool
+	// Start is the time of the first fix; defaults to now.
+	Start time.Time
+	Seed  int64
+}
+
+// defaultSimSpeed is a relaxed bike ride in m/s.
+const defaultSimSpeed = 5
+
+// NewSimGPS streams NMEA for a ride along a route.
+func NewSimGPS(cfg SimConfig) (*GPS, error) {
+	s, err := newSim(cfg)
+	if err != nil {
+		return nil, err
+	}
+	pr, pw := io.Pipe()
+	go func() { pw.CloseWithError(s.run(pw)) }()
+	return newGPS(pr, Config{}), nil
+}
+
+type sim struct {
+	cfg SimConfig
+	rnd *rand.Rand
+	// dists are the distances of the route points from the start.
+	dists []float64
+}
+
+func newSim(cfg SimConfig) (*sim, error) {
+	if len(cfg.Route) < 2 {
+		return nil, errors.New("gps: route needs at least two points")
+	}
+	if cfg.Rate == 0 {
+		cfg.Rate = 1
+	}
+	if cfg.Rate < 0 || cfg.Rate > 1000 {
+		return nil, fmt.Errorf("gps: bad fix rate %d", cfg.Rate)
+	}
+	if len(cfg.Speeds) == 0 {
+		cfg.Speeds = []SimSpeed{{0, defaultSimSpeed}}
+	}
+	// The ride must keep moving to reach the end of the route.
+	if cfg.Speeds[0].At > 0 {
+		return nil, fmt.Errorf("gps: speed profile starts at %gm, not 0m", cfg.Speeds[0].At)
+	}
+	for i, sp := range cfg.Speeds {
+		if !(sp.Speed > 0) || (i > 0 && sp.At < cfg.Speeds[i-1].At) {
+			return nil, fmt.Errorf("gps: bad speed profile at %gm", sp.At)
+		}
+	}
+	dists := make([]float64, len(cfg.Route))
+	for i := 1; i < len(cfg.Route); i++ {
+		dists[i] = dists[i-1] + geo.Distance(cfg.Route[i-1].point(), cfg.Route[i].point())
+	}
+	if dists[len(dists)-1] == 0 {
+		return nil, errors.New("gps: route has no length")
+	}
+	return &sim{cfg: cfg, rnd: rand.New(rand.NewSource(cfg.Seed)), dists: dists}, nil
+}
+
+func (s *sim) run(w io.Writer) error {
+	dt := time.Second / time.Duration(s.cfg.Rate)
+	t := s.cfg.Start
+	if t.IsZero() {
+		t = time.Now()
+	}
+	var tick <-chan time.Time
+	if s.cfg.Realtime {
+		ticker := time.NewTicker(dt)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	coldUntil, dropUntil := t.Add(s.cfg.ColdStart), time.Time{}
+	total := s.dists[len(s.dists)-1]
+	d := 0.0
+	for {
+		p, course := s.position(d)
+		speed := s.speed(d)
+		if t.After(dropUntil) && s.rnd.Float64() < s.cfg.Dropouts*dt.Seconds() {
+			dropUntil = t.Add(s.cfg.DropoutTime)
+		}
+		fix := Fix{Time: t}
+		if !t.Before(coldUntil) && !t.Before(dropUntil) {
+			p = s.noisy(p)
+			fix = Fix{
+				Time:       t,
+				Valid:      true,
+				Latitude:   p.Lat,
+				Longitude:  p.Lon,
+				Altitude:   p.Alt,
+				Speed:      speed,
+				Course:     course,
+				MagVar:     math.NaN(),
+				Satellites: 8,
+				HDOP:       0.9,
+			}
+		}
+		out := append(Encoder{}.RMC(fix), Encoder{}.GGA(fix)...)
+		if _, err := w.Write(out); err != nil {
+			return err
+		}
+		if d >= total && !s.cfg.Loop {
+			return io.EOF
+		}
+		if d += speed * dt.Seconds(); d >= total {
+			if s.cfg.Loop {
+				d = math.Mod(d, total)
+			} else {
+				d = total
+			}
+		}
+		t = t.Add(dt)
+		if tick != nil {
+			<-tick
+		}
+	}
+}
+
+// speed is the profile speed at a distance along the route.
+func (s *sim) speed(d float64) (v float64) {
+	for _, sp := range s.cfg.Speeds {
+		if sp.At > d {
+			break
+		}
+		v = sp.Speed
+	}
+	return v
+}
+
+// position interpolates the point and course at a distance along the
+// route.
+func (s *sim) position(d float64) (RoutePoint, float64) {
+	i := 1
+	for i < len(s.dists)-1 && s.dists[i] <= d {
+		i++
+	}
+	a, b := s.cfg.Route[i-1], s.cfg.Route[i]
+	f := 0.0
+	if seg := s.dists[i] - s.dists[i-1]; seg > 0 {
+		f = math.Min((d-s.dists[i-1])/seg, 1)
+	}
+	p := RoutePoint{
+		Lat: a.Lat + f*(b.Lat-a.Lat),
+		Lon: a.Lon + f*(b.Lon-a.Lon),
+		Alt: a.Alt + f*(b.Alt-a.Alt),
+	}
+	return p, geo.Bearing(a.point(), b.point())
+}
+
+// noisy offsets a point by gaussian noise in meters.
+func (s *sim) noisy(p RoutePoint) RoutePoint {
+	if s.cfg.Noise == 0 {
+		return p
+	}
+	n, e := s.rnd.NormFloat64()*s.cfg.Noise, s.rnd.NormFloat64()*s.cfg.Noise
+	p.Lat += n / geo.EarthRadius * 180 / math.Pi
+	p.Lon += e / (geo.EarthRadius * math.Cos(p.Lat*math.Pi/180)) * 180 / math.Pi
+	return p
+}
+
+// point is the position of a route point.
+func (p RoutePoint) point() geo.Point { return geo.Point{Lat: p.Lat, Lon: p.Lon} }
+
+// ParseSpeedProfile parses a speed in m/s (e.g., "5") or speeds from
+// distances along the route in meters (e.g., "0:5,1000:8").
+func ParseSpeedProfile(s string) ([]SimSpeed, error) {
+	var ret []SimSpeed
+	for _, f := range strings.Split(s, ",") {
+		var sp SimSpeed
+		var err error
+		at, speed := "0", f
+		if i := strings.IndexByte(f, ':'); i >= 0 {
+			at, speed = f[:i], f[i+1:]
+		}
+		if sp.At, err = strconv.ParseFloat(at, 64); err != nil {
+			return nil, fmt.Errorf("gps: bad speed profile %q", s)
+		}
+		if sp.Speed, err = strconv.ParseFloat(speed, 64); err != nil {
+			return nil, fmt.Errorf("gps: bad speed profile %q", s)
+		}
+		ret = append(ret, sp)
+	}
+	return ret, nil
+}
+
+// ReadRoute reads a GPX or GeoJSON route by its file extension.
+func ReadRoute(p string) ([]RoutePoint, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	switch strings.ToLower(filepath.Ext(p)) {
+	case ".gpx":
+		return ReadGPX(f)
+	case ".geojson", ".json":
+		return ReadGeoJSON(f)
+	}
+	return nil, fmt.Errorf("gps: unknown route format %q", p)
+}
+
+type gpxPoint struct {
+	Lat float64  `xml:"lat,attr"`
+	Lon float64  `xml:"lon,attr"`
+	Ele *float64 `xml:"ele"`
+}
+
+// ReadGPX reads the track points, or else the route points, of a GPX
+// file.
+func ReadGPX(r io.Reader) ([]RoutePoint, error) {
+	var gpx struct {
+		Trk []struct {
+			Seg []struct {
+				Pts []gpxPoint `xml:"trkpt"`
+			} `xml:"trkseg"`
+		} `xml:"trk"`
+		Rte []struct {
+			Pts []gpxPoint `xml:"rtept"`
+		} `xml:"rte"`
+	}
+	if err := xml.NewDecoder(r).Decode(&gpx); err != nil {
+		return nil, err
+	}
+	var pts []gpxPoint
+	for _, trk := range gpx.Trk {
+		for _, seg := range trk.Seg {
+			pts = append(pts, seg.Pts...)
+		}
+	}
+	if len(pts) == 0 {
+		for _, rte := range gpx.Rte {
+			pts = append(pts, rte.Pts...)
+		}
+	}
+	ret := make([]RoutePoint, len(pts))
+	for i, pt := range pts {
+		ret[i] = RoutePoint{Lat: pt.Lat, Lon: pt.Lon, Alt: math.NaN()}
+		if pt.Ele != nil {
+			ret[i].Alt = *pt.Ele
+		}
+	}
+	return ret, nil
+}
+
+type geoJSON struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Geometry    *geoJSON        `json:"geometry"`
+	Features    []geoJSON       `json:"features"`
+}
+
+// ReadGeoJSON reads the LineStrings of a GeoJSON file.
+func ReadGeoJSON(r io.Reader) ([]RoutePoint, error) {
+	var g geoJSON
+	if err := json.NewDecoder(r).Decode(&g); err != nil {
+		return nil, err
+	}
+	return g.points()
+}
+
+func (g *geoJSON) points() (ret []RoutePoint, err error) {
+	switch g.Type {
+	case "FeatureCollection":
+		for i := range g.Features {
+			pts, err := g.Features[i].points()
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, pts...)
+		}
+		return ret, nil
+	case "Feature":
+		if g.Geometry == nil {
+			return nil, nil
+		}
+		return g.Geometry.points()
+	case "LineString":
+		var coords [][]float64
+		if err := json.Unmarshal(g.Coordinates, &coords); err != nil {
+			return nil, err
+		}
+		return geoJSONPoints(coords)
+	case "MultiLineString":
+		var lines [][][]float64
+		if err := json.Unmarshal(g.Coordinates, &lines); err != nil {
+			return nil, err
+		}
+		for _, coords := range lines {
+			pts, err := geoJSONPoints(coords)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, pts...)
+		}
+		return ret, nil
+	}
+	// Other geometries are not routes.
+	return nil, nil
+}
+
+// geoJSONPoints converts [lon, lat, alt] positions.
+func geoJSONPoints(coords [][]float64) ([]RoutePoint, error) {
+	ret := make([]RoutePoint, len(coords))
+	for i, c := range coords {
+		if len(c) < 2 {
+			return nil, errors.New("gps: bad geojson position")
+		}
+		ret[i] = RoutePoint{Lat: c[1], Lon: c[0], Alt: math.NaN()}
+		if len(c) > 2 {
+			ret[i].Alt = c[2]
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,179 @@
+package gps
+
+import (
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+const simGPX = `<?xml version="1.0"?>
+<gpx version="1.1" xmlns="http://www.topografix.com/GPX/1/1">
+ <trk><trkseg>
+  <trkpt lat="48.1173" lon="11.5167"><ele>545.4</ele></trkpt>
+  <trkpt lat="48.1182" lon="11.5167"><ele>546.4</ele></trkpt>
+  <trkpt lat="48.1182" lon="11.5180"><ele>547.4</ele></trkpt>
+ </trkseg></trk>
+</gpx>`
+
+const simGeoJSON = `{"type":"FeatureCollection","features":[
+ {"type":"Feature","geometry":{"type":"Point","coordinates":[0,0]}},
+ {"type":"Feature","geometry":{"type":"LineString","coordinates":[
+  [11.5167,48.1173,545.4],[11.5167,48.1182,546.4],[11.5180,48.1182,547.4]]}}]}`
+
+func readSim(t *testing.T, cfg SimConfig) (msgs []NMEA, st Stats) {
+	g, err := NewSimGPS(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for msg := range g.NMEA() {
+		msgs = append(msgs, msg)
+	}
+	return msgs, g.Stats()
+}
+
+func TestReadRoute(t *testing.T) {
+	gpx, err := ReadGPX(strings.NewReader(simGPX))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gj, err := ReadGeoJSON(strings.NewReader(simGeoJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gpx) != 3 || len(gj) != 3 {
+		t.Fatalf("expected 3 points, got %d and %d", len(gpx), len(gj))
+	}
+	for i := range gpx {
+		if gpx[i] != gj[i] {
+			t.Errorf("#%d: gpx %+v != geojson %+v", i, gpx[i], gj[i])
+		}
+	}
+}
+
+func TestSimGPS(t *testing.T) {
+	route, _ := ReadGPX(strings.NewReader(simGPX))
+	start := time.Date(2018, 3, 12, 2, 55, 3, 0, time.UTC)
+	msgs, st := readSim(t, SimConfig{
+		Route:  route,
+		Speeds: []SimSpeed{{0, 10}},
+		Rate:   2,
+		Start:  start,
+	})
+	if st.BadChecksum != 0 || st.Unparsable != 0 {
+		t.Fatalf("bad sentences: %+v", st)
+	}
+	// 100m north then 96.5m east at 10 m/s, two fixes a second.
+	var rmcs []*RMC
+	for _, msg := range msgs {
+		switch m := msg.Msg().(type) {
+		case *GGA:
+			if alt := m.Altitude(); alt < 545.4 || alt > 547.4 {
+				t.Errorf("bad altitude in %q", msg.Line())
+			}
+		case *RMC:
+			rmcs = append(rmcs, m)
+		}
+	}
+	if len(rmcs) != 41 || len(msgs) != 82 {
+		t.Fatalf("expected 41 fixes, got %d (%d messages)", len(rmcs), len(msgs))
+	}
+	for i, rmc := range rmcs {
+		if want := start.Add(time.Duration(i) * 500 * time.Millisecond); !rmc.Fix().Equal(want) {
+			t.Errorf("#%d: wanted %v, got %v", i, want, rmc.Fix())
+		}
+	}
+	if rmcs[1].Course() != 0 || math.Abs(rmcs[1].Speed()-10) > 0.01 {
+		t.Errorf("expected north at 10 m/s, got %g at %g", rmcs[1].Course(), rmcs[1].Speed())
+	}
+	if c := rmcs[30].Course(); math.Abs(c-90) > 0.1 {
+		t.Errorf("expected east, got %g", c)
+	}
+	last := rmcs[len(rmcs)-1]
+	if math.Abs(last.Latitude()-48.1182) > 1e-6 || math.Abs(last.Longitude()-11.5180) > 1e-6 {
+		t.Errorf("expected route end, got %g,%g", last.Latitude(), last.Longitude())
+	}
+}
+
+func TestSimGPSOutages(t *testing.T) {
+	route, _ := ReadGPX(strings.NewReader(simGPX))
+	msgs, _ := readSim(t, SimConfig{
+		Route:       route,
+		Speeds:      []SimSpeed{{0, 20}},
+		ColdStart:   3 * time.Second,
+		Dropouts:    1,
+		DropoutTime: 2 * time.Second,
+		Noise:       3,
+		Seed:        1,
+	})
+	var valid []bool
+	for _, msg := range msgs {
+		if _, ok := msg.Msg().(*RMC); ok {
+			valid = append(valid, msg.Valid())
+		}
+	}
+	// Cold start, then a fix between two second dropouts.
+	want := []bool{false, false, false, false, false, true, false, false, true, false, false}
+	if len(valid) != len(want) {
+		t.Fatalf("wanted %v, got %v", want, valid)
+	}
+	for i := range want {
+		if valid[i] != want[i] {
+			t.Errorf("wanted %v, got %v", want, valid)
+			break
+		}
+	}
+}
+
+// TestSimRouteEnd checks that a ride ends with the route.
+func TestSimRouteEnd(t *testing.T) {
+	route, _ := ReadGPX(strings.NewReader(simGPX))
+	g, err := NewSimGPS(SimConfig{Route: route, Speeds: []SimSpeed{{0, 5}, {50, 20}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	timeout := time.After(5 * time.Second)
+	for done := false; !done; {
+		select {
+		case _, ok := <-g.NMEA():
+			done = !ok
+		case <-timeout:
+			g.Close()
+			t.Fatal("route did not end")
+		}
+	}
+	if err := g.Close(); err != io.EOF {
+		t.Errorf("wanted %v, got %v", io.EOF, err)
+	}
+}
+
+func TestSimBadSpeeds(t *testing.T) {
+	route, _ := ReadGPX(strings.NewReader(simGPX))
+	tts := [][]SimSpeed{
+		{{0, 0}},
+		{{0, 5}, {50, 0}},
+		{{0, -1}},
+		{{0, math.NaN()}},
+		{{50, 5}},
+		{{0, 5}, {100, 8}, {50, 5}},
+	}
+	for i, tt := range tts {
+		if _, err := NewSimGPS(SimConfig{Route: route, Speeds: tt}); err == nil {
+			t.Errorf("#%d: expected error for %+v", i, tt)
+		}
+	}
+}
+
+func TestParseSpeedProfile(t *testing.T) {
+	sp, err := ParseSpeedProfile("0:5,1000:8.5")
+	if err != nil || len(sp) != 2 || sp[1] != (SimSpeed{1000, 8.5}) {
+		t.Errorf("bad profile %+v (%v)", sp, err)
+	}
+	if sp, err := ParseSpeedProfile("7"); err != nil || len(sp) != 1 || sp[0] != (SimSpeed{0, 7}) {
+		t.Errorf("bad constant profile %+v (%v)", sp, err)
+	}
+	if _, err := ParseSpeedProfile("0:fast"); err == nil {
+		t.Errorf("expected error")
+	}
+}
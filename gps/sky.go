@@ -0,0 +1,188 @@
+package gps
+
+import (
+	"math"
+	"sort"
+)
+
+// Sky is a snapshot of the satellites in view and the fix geometry.
+type Sky struct {
+	Satellites []Satellite
+	Mode       FixMode
+	PDOP       float64
+	HDOP       float64
+	VDOP       float64
+}
+
+// Tracked is the number of satellites with a signal.
+func (s *Sky) Tracked() (n int) {
+	for _, sat := range s.Satellites {
+		if sat.SNR > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// Active is the number of satellites used in the fix.
+func (s *Sky) Active() (n int) {
+	for _, sat := range s.Satellites {
+		if sat.Active {
+			n++
+		}
+	}
+	return n
+}
+
+// SkyView assembles GSV groups, GSA sentences, UBX-NAV-SAT messages,
+// and gpsd reports into a Sky.
+type SkyView struct {
+	// pending holds incomplete GSV groups by talker and signal.
+	pending map[string][]Satellite
+	// views holds the last complete GSV group by talker and signal,
+	// and the last NAV-SAT and gpsd SKY satellites.
+	views map[string][]Satellite
+	// active holds the PRNs used in the fix by talker.
+	active map[string]map[int]struct{}
+	// mode and the DOPs are from the last GSA or gpsd report.
+	mode             FixMode
+	pdop, hdop, vdop float64
+	// lastGSA is the talker of the previous sentence if it was a GSA.
+	lastGSA string
+}
+
+// gsaSystems maps NMEA 4.1 system ids to talker ids.
+var gsaSystems = map[string]string{
+	"1": "GP",
+	"2": "GL",
+	"3": "GA",
+	"4": "BD",
+}
+
+func NewSkyView() *SkyView {
+	return &SkyView{
+		pending: make(map[string][]Satellite),
+		views:   make(map[string][]Satellite),
+		active:  make(map[string]map[int]struct{}),
+		pdop:    math.NaN(),
+		hdop:    math.NaN(),
+		vdop:    math.NaN(),
+	}
+}
+
+// Update adds a message to the view. Returns true when the sky changes.
+func (sv *SkyView) Update(n NMEA) bool {
+	talker, lastGSA := n.Talker(), sv.lastGSA
+	sv.lastGSA = ""
+	switch msg := n.Msg().(type) {
+	case *GSV:
+		return sv.updateGSV(talker, msg)
+	case *NavSAT:
+		sv.views["UBX"] = msg.Satellites()
+		return true
+	case *SKY:
+		sv.views["GPSD"] = msg.Satellites()
+		if pdop := msg.PDOP(); pdop == pdop {
+			sv.pdop = pdop
+		}
+		if hdop := msg.HDOP(); hdop == hdop {
+			sv.hdop = hdop
+		}
+		if vdop := msg.VDOP(); vdop == vdop {
+			sv.vdop = vdop
+		}
+		return true
+	case *TPV:
+		if mode := msg.Mode(); mode != sv.mode {
+			sv.mode = mode
+			return true
+		}
+		return false
+	case *GSA:
+		if sys, ok := gsaSystems[msg.System()]; ok {
+			talker = sys
+		}
+		sv.lastGSA = talker
+		// Multi-constellation receivers send a burst of GSAs under
+		// the same talker; merge them instead of overwriting.
+		if lastGSA != talker {
+			sv.active[talker] = make(map[int]struct{})
+		}
+		for _, prn := range msg.PRNs() {
+			sv.active[talker][prn] = struct{}{}
+		}
+		sv.mode = msg.Mode()
+		sv.pdop, sv.hdop, sv.vdop = msg.PDOP(), msg.HDOP(), msg.VDOP()
+		return true
+	}
+	return false
+}
+
+func (sv *SkyView) updateGSV(talker string, msg *GSV) bool {
+	key := talker + msg.Signal()
+	num := msg.Sentence()
+	sats := sv.pending[key]
+	if num == 1 {
+		sats = nil
+	} else if sats == nil {
+		// Missed the start of the group.
+		return false
+	}
+	for _, sat := range msg.Satellites() {
+		sat.Talker = talker
+		sats = append(sats, sat)
+	}
+	if num < msg.Sentences() {
+		sv.pending[key] = sats
+		return false
+	}
+	delete(sv.pending, key)
+	if sats == nil {
+		sats = []Satellite{}
+	}
+	sv.views[key] = sats
+	return true
+}
+
+// Sky returns the current view of the sky.
+func (sv *SkyView) Sky() Sky {
+	sky := Sky{Mode: sv.mode, PDOP: sv.pdop, HDOP: sv.hdop, VDOP: sv.vdop}
+	// Satellites may be listed once per signal; keep the strongest.
+	type satKey struct {
+		talker string
+		prn    int
+	}
+	best := make(map[satKey]Satellite)
+	for _, sats := range sv.views {
+		for _, sat := range sats {
+			k := satKey{sat.Talker, sat.PRN}
+			old, ok := best[k]
+			if ok && sat.SNR <= old.SNR {
+				sat, old = old, sat
+			}
+			sat.Active = sat.Active || old.Active
+			best[k] = sat
+		}
+	}
+	for _, sat := range best {
+		sat.Active = sat.Active || sv.isActive(sat)
+		sky.Satellites = append(sky.Satellites, sat)
+	}
+	sort.Slice(sky.Satellites, func(i, j int) bool {
+		si, sj := sky.Satellites[i], sky.Satellites[j]
+		if si.Talker != sj.Talker {
+			return si.Talker < sj.Talker
+		}
+		return si.PRN < sj.PRN
+	})
+	return sky
+}
+
+func (sv *SkyView) isActive(sat Satellite) bool {
+	for _, talker := range []string{sat.Talker, "GN"} {
+		if _, ok := sv.active[talker][sat.PRN]; ok {
+			return true
+		}
+	}
+	return false
+}
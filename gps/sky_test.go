@@ -0,0 +1,75 @@
+package gps
+
+import (
+	"strings"
+	"testing"
+)
+
+func readSky(lines ...string) (*SkyView, int) {
+	g := newGPS(&rc{strings.NewReader(strings.Join(lines, "\n") + "\n")}, Config{})
+	sv, updates := NewSkyView(), 0
+	for msg := range g.NMEA() {
+		if sv.Update(msg) {
+			updates++
+		}
+	}
+	return sv, updates
+}
+
+func TestSkyView(t *testing.T) {
+	sv, _ := readSky(
+		"$GPGSV,3,1,09,01,40,083,46,02,17,308,41,12,07,344,39,14,22,228,45*75",
+		"$GPGSV,3,2,09,15,,,,17,55,120,,19,10,045,22,24,70,300,48*46",
+		"$GPGSV,3,3,09,32,05,010,*45",
+		"$GLGSV,1,1,02,65,30,100,35,66,12,200,*61",
+		"$GNGSA,A,3,01,02,14,24,,,,,,,,,1.8,0.9,1.5*28",
+		"$GNGSA,A,3,65,,,,,,,,,,,,1.8,0.9,1.5*2B",
+	)
+	sky := sv.Sky()
+	if n := len(sky.Satellites); n != 11 {
+		t.Fatalf("expected 11 satellites, got %d: %+v", n, sky.Satellites)
+	}
+	if n := sky.Tracked(); n != 7 {
+		t.Errorf("expected 7 tracked satellites, got %d", n)
+	}
+	if n := sky.Active(); n != 5 {
+		t.Errorf("expected 5 active satellites, got %d", n)
+	}
+	if sky.Mode != Fix3D {
+		t.Errorf("expected 3D fix, got %d", sky.Mode)
+	}
+	if sky.PDOP != 1.8 || sky.HDOP != 0.9 || sky.VDOP != 1.5 {
+		t.Errorf("wrong dop %g/%g/%g", sky.PDOP, sky.HDOP, sky.VDOP)
+	}
+	want := Satellite{Talker: "GP", PRN: 14, Elevation: 22, Azimuth: 228, SNR: 45, Active: true}
+	if sat := sky.Satellites[5]; sat != want {
+		t.Errorf("wanted %+v, got %+v", want, sat)
+	}
+	if sat := sky.Satellites[0]; sat.Talker != "GL" || sat.PRN != 65 || !sat.Active {
+		t.Errorf("expected active GLONASS 65, got %+v", sat)
+	}
+}
+
+func TestSkyViewPartialGroup(t *testing.T) {
+	_, updates := readSky(
+		"$GPGSV,3,2,09,15,,,,17,55,120,,19,10,045,22,24,70,300,48*46",
+		"$GPGSV,3,3,09,32,05,010,*45",
+	)
+	if updates != 0 {
+		t.Errorf("expected no updates from partial group, got %d", updates)
+	}
+}
+
+func TestSkyViewNoSky(t *testing.T) {
+	sv, updates := readSky(
+		"$GPGSV,1,1,00*79",
+		"$GNGSA,A,1,,,,,,,,,,,,,99.99,99.99,99.99,1*33",
+	)
+	if updates != 2 {
+		t.Fatalf("expected 2 updates, got %d", updates)
+	}
+	sky := sv.Sky()
+	if len(sky.Satellites) != 0 || sky.Mode != FixNone {
+		t.Errorf("expected empty sky without fix, got %+v", sky)
+	}
+}
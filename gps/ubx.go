@@ -0,0 +1,196 @@
+package gps
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+// UBX frames are: 0xB5 0x62 class id length(LE16) payload ck_a ck_b.
+const (
+	ubxSync1 = 0xb5
+	ubxSync2 = 0x62
+
+	ubxHeaderLen = 6
+	// maxUBXPayload bounds frames; NAV-SAT with 255 satellites fits.
+	maxUBXPayload = 8 + 12*255
+
+	ubxClassNAV = 0x01
+	ubxNavPVT   = 0x07
+	ubxNavSAT   = 0x35
+
+	ubxNavPVTLen = 92
+)
+
+// ubxChecksum is the 8-bit Fletcher checksum over class, id, length,
+// and payload.
+func ubxChecksum(b []byte) (ckA, ckB byte) {
+	for _, c := range b {
+		ckA += c
+		ckB += ckA
+	}
+	return ckA, ckB
+}
+
+// ubxFrame builds a frame around a payload.
+func ubxFrame(class, id byte, payload []byte) []byte {
+	frame := []byte{ubxSync1, ubxSync2, class, id, 0, 0}
+	binary.LittleEndian.PutUint16(frame[4:], uint16(len(payload)))
+	frame = append(frame, payload...)
+	ckA, ckB := ubxChecksum(frame[2:])
+	return append(frame, ckA, ckB)
+}
+
+// decodeUBX decodes a frame's payload into a message.
+func decodeUBX(class, id byte, payload []byte) NMEAi {
+	switch {
+	case class == ubxClassNAV && id == ubxNavPVT && len(payload) >= ubxNavPVTLen:
+		return &NavPVT{payload: payload}
+	case class == ubxClassNAV && id == ubxNavSAT && len(payload) >= 8:
+		return &NavSAT{payload: payload}
+	}
+	return &nmeaUnk{}
+}
+
+// PVTFixType is the NAV-PVT fix type.
+type PVTFixType int
+
+const (
+	PVTNoFix PVTFixType = iota
+	PVTDeadReckoning
+	PVTFix2D
+	PVTFix3D
+	PVTFixGNSSDeadReckoning
+	PVTTimeOnly
+)
+
+// NavPVT is the UBX-NAV-PVT navigation solution.
+type NavPVT struct {
+	payload []byte
+}
+
+func (p *NavPVT) u8(off int) uint8   { return p.payload[off] }
+func (p *NavPVT) u16(off int) uint16 { return binary.LittleEndian.Uint16(p.payload[off:]) }
+func (p *NavPVT) u32(off int) uint32 { return binary.LittleEndian.Uint32(p.payload[off:]) }
+func (p *NavPVT) i32(off int) int32  { return int32(p.u32(off)) }
+
+// Fix is the UTC time of the solution; zero if the receiver has no
+// valid date and time.
+func (p *NavPVT) Fix() (ret time.Time) {
+	// valid: validDate | validTime
+	if p.u8(11)&0x3 != 0x3 {
+		return ret
+	}
+	t := time.Date(
+		int(p.u16(4)),
+		time.Month(p.u8(6)),
+		int(p.u8(7)),
+		int(p.u8(8)),
+		int(p.u8(9)),
+		int(p.u8(10)),
+		0,
+		time.UTC)
+	return t.Add(time.Duration(p.i32(16)))
+}
+
+func (p *NavPVT) Longitude() float64 { return float64(p.i32(24)) * 1e-7 }
+func (p *NavPVT) Latitude() float64  { return float64(p.i32(28)) * 1e-7 }
+
+// Speed is the 2D ground speed in m/s.
+func (p *NavPVT) Speed() float64 { return float64(p.i32(60)) / 1e3 }
+
+// Course is the 2D heading of motion in degrees.
+func (p *NavPVT) Course() float64 { return float64(p.i32(64)) * 1e-5 }
+
+func (p *NavPVT) MagVar() float64 {
+	// valid: validMag
+	if p.u8(11)&0x8 == 0 {
+		return math.NaN()
+	}
+	return float64(int16(p.u16(88))) * 1e-2
+}
+
+// Valid is set for a 2D or 3D fix within the receiver's accuracy limits.
+func (p *NavPVT) Valid() bool {
+	// flags: gnssFixOK
+	if p.u8(21)&0x1 == 0 {
+		return false
+	}
+	switch p.FixType() {
+	case PVTFix2D, PVTFix3D, PVTFixGNSSDeadReckoning:
+		return true
+	}
+	return false
+}
+
+func (p *NavPVT) FixType() PVTFixType { return PVTFixType(p.u8(20)) }
+
+// Satellites is the number of satellites used in the solution.
+func (p *NavPVT) Satellites() int { return int(p.u8(23)) }
+
+// Altitude is the height above mean sea level in meters.
+func (p *NavPVT) Altitude() float64 { return float64(p.i32(36)) / 1e3 }
+
+// Height is the height above the WGS84 ellipsoid in meters.
+func (p *NavPVT) Height() float64 { return float64(p.i32(32)) / 1e3 }
+
+// HorizontalAccuracy is the horizontal accuracy estimate in meters.
+func (p *NavPVT) HorizontalAccuracy() float64 { return float64(p.u32(40)) / 1e3 }
+
+// VerticalAccuracy is the vertical accuracy estimate in meters.
+func (p *NavPVT) VerticalAccuracy() float64 { return float64(p.u32(44)) / 1e3 }
+
+// SpeedAccuracy is the speed accuracy estimate in m/s.
+func (p *NavPVT) SpeedAccuracy() float64 { return float64(p.u32(68)) / 1e3 }
+
+// TimeAccuracy is the time accuracy estimate.
+func (p *NavPVT) TimeAccuracy() time.Duration { return time.Duration(p.u32(12)) }
+
+// Velocity is the north, east, and down velocity in m/s.
+func (p *NavPVT) Velocity() (n, e, d float64) {
+	return float64(p.i32(48)) / 1e3, float64(p.i32(52)) / 1e3, float64(p.i32(56)) / 1e3
+}
+
+func (p *NavPVT) PDOP() float64 { return float64(p.u16(76)) * 1e-2 }
+
+// ubxTalkers maps UBX GNSS ids to NMEA talker ids.
+var ubxTalkers = map[byte]string{
+	0: "GP",
+	1: "GP", // SBAS is reported by GPS talkers in NMEA.
+	2: "GA",
+	3: "BD",
+	5: "GQ",
+	6: "GL",
+}
+
+// NavSAT is the UBX-NAV-SAT satellite information.
+type NavSAT struct {
+	nmeaUnk
+	payload []byte
+}
+
+// Satellites lists the satellites known to the receiver.
+func (s *NavSAT) Satellites() []Satellite {
+	n := int(s.payload[5])
+	if max := (len(s.payload) - 8) / 12; n > max {
+		n = max
+	}
+	ret := make([]Satellite, n)
+	for i := range ret {
+		sv := s.payload[8+12*i : 8+12*(i+1)]
+		talker, ok := ubxTalkers[sv[0]]
+		if !ok {
+			talker = "GN"
+		}
+		ret[i] = Satellite{
+			Talker:    talker,
+			PRN:       int(sv[1]),
+			SNR:       int(sv[2]),
+			Elevation: int(int8(sv[3])),
+			Azimuth:   int(int16(binary.LittleEndian.Uint16(sv[4:]))),
+			// flags: svUsed
+			Active: binary.LittleEndian.Uint32(sv[8:])&0x8 != 0,
+		}
+	}
+	return ret
+}
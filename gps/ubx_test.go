@@ -0,0 +1,111 @@
+package gps
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+func navPVTPayload() []byte {
+	p := make([]byte, ubxNavPVTLen)
+	le := binary.LittleEndian
+	le.PutUint16(p[4:], 2018)
+	p[6], p[7], p[8], p[9], p[10] = 3, 12, 2, 55, 3
+	p[11] = 0x7 | 0x8 // valid date, time, fully resolved, mag
+	le.PutUint32(p[12:], 30)
+	le.PutUint32(p[16:], uint32(250000000))
+	p[20], p[21], p[23] = byte(PVTFix3D), 0x1, 9
+	lon := int32(-1111888889)
+	le.PutUint32(p[24:], uint32(lon))
+	le.PutUint32(p[28:], uint32(int32(481173000)))
+	le.PutUint32(p[32:], uint32(int32(592300)))
+	le.PutUint32(p[36:], uint32(int32(545400)))
+	le.PutUint32(p[40:], 2500)
+	le.PutUint32(p[60:], 5500)
+	le.PutUint32(p[64:], 8440000)
+	le.PutUint16(p[76:], 180)
+	magDec := int16(-310)
+	le.PutUint16(p[88:], uint16(magDec))
+	return p
+}
+
+func TestUBXDemux(t *testing.T) {
+	pvt := ubxFrame(ubxClassNAV, ubxNavPVT, navPVTPayload())
+	sat := make([]byte, 8+2*12)
+	sat[5] = 2
+	sat[8], sat[9], sat[10], sat[11] = 0, 14, 45, 22
+	binary.LittleEndian.PutUint16(sat[12:], 228)
+	binary.LittleEndian.PutUint32(sat[16:], 0x8)
+	sat[20], sat[21], sat[22], sat[23] = 6, 65, 0, 30
+	navsat := ubxFrame(ubxClassNAV, ubxNavSAT, sat)
+	unk := ubxFrame(0x0a, 0x04, []byte("1.00"))
+	bad := ubxFrame(ubxClassNAV, ubxNavPVT, navPVTPayload())
+	bad[len(bad)-1]++
+
+	var b bytes.Buffer
+	b.WriteString("$GPRMC,043019.00,V,,,,,,,120318,,,N*7B\r\n")
+	b.Write(pvt)
+	b.Write(bad)
+	b.Write(navsat)
+	b.WriteString("$GPRMC,043019.00,V,,,,,,,120318,,,N*7B\r\n")
+	b.Write(unk)
+
+	g := newGPS(&rc{&b}, Config{})
+	var msgs []NMEA
+	for msg := range g.NMEA() {
+		msgs = append(msgs, msg)
+	}
+	if len(msgs) != 5 {
+		t.Fatalf("expected 5 messages, got %d", len(msgs))
+	}
+	if msgs[0].UBX() != nil || msgs[3].UBX() != nil {
+		t.Errorf("expected NMEA sentences at 0 and 3")
+	}
+	if !bytes.Equal(msgs[1].UBX(), pvt) || msgs[1].Line() != "" {
+		t.Errorf("expected raw NAV-PVT frame")
+	}
+	if !bytes.Equal(msgs[4].UBX(), unk) {
+		t.Errorf("expected raw unknown frame")
+	}
+	want := Stats{Lines: 2, Frames: 4, BadChecksum: 1}
+	if st := g.Stats(); st != want {
+		t.Errorf("wanted %+v, got %+v", want, st)
+	}
+
+	p, ok := msgs[1].Msg().(*NavPVT)
+	if !ok {
+		t.Fatalf("expected NavPVT, got %T", msgs[1].Msg())
+	}
+	fix := time.Date(2018, 3, 12, 2, 55, 3, 250000000, time.UTC)
+	if !p.Fix().Equal(fix) {
+		t.Errorf("wanted fix %v, got %v", fix, p.Fix())
+	}
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-6 }
+	if !near(p.Latitude(), 48.1173) || !near(p.Longitude(), -111.1888889) {
+		t.Errorf("wrong position %g,%g", p.Latitude(), p.Longitude())
+	}
+	if !p.Valid() || p.Satellites() != 9 || p.FixType() != PVTFix3D {
+		t.Errorf("wrong fix state")
+	}
+	if !near(p.Speed(), 5.5) || !near(p.Course(), 84.4) || !near(p.MagVar(), -3.1) {
+		t.Errorf("wrong motion %g/%g/%g", p.Speed(), p.Course(), p.MagVar())
+	}
+	if !near(p.Altitude(), 545.4) || !near(p.HorizontalAccuracy(), 2.5) || !near(p.PDOP(), 1.8) {
+		t.Errorf("wrong accuracy %g/%g/%g", p.Altitude(), p.HorizontalAccuracy(), p.PDOP())
+	}
+
+	sv := NewSkyView()
+	if !sv.Update(msgs[2]) {
+		t.Fatalf("expected NAV-SAT to update sky")
+	}
+	sky := sv.Sky()
+	if len(sky.Satellites) != 2 || sky.Active() != 1 || sky.Tracked() != 1 {
+		t.Fatalf("wrong sky %+v", sky)
+	}
+	wantSat := Satellite{Talker: "GP", PRN: 14, Elevation: 22, Azimuth: 228, SNR: 45, Active: true}
+	if sky.Satellites[1] != wantSat {
+		t.Errorf("wanted %+v, got %+v", wantSat, sky.Satellites[1])
+	}
+}
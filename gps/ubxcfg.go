@@ -0,0 +1,126 @@
+package gps
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	ubxClassACK = 0x05
+	ubxAckACK   = 0x01
+
+	ubxClassCFG = 0x06
+	ubxCfgMSG   = 0x01
+	ubxCfgRATE  = 0x08
+	ubxCfgGNSS  = 0x3e
+
+	ubxClassNMEA = 0xf0
+)
+
+// ubxSentences are the UBX message ids of NMEA sentences.
+var ubxSentences = []struct {
+	name string
+	id   byte
+}{
+	{"GGA", 0x00},
+	{"GLL", 0x01},
+	{"GSA", 0x02},
+	{"GSV", 0x03},
+	{"RMC", 0x04},
+	{"VTG", 0x05},
+	{"ZDA", 0x08},
+}
+
+// ubxGNSSBlock is a CFG-GNSS configuration block.
+type ubxGNSSBlock struct {
+	gnssID   byte
+	resTrkCh byte
+	maxTrkCh byte
+}
+
+// ubxConstellations are the CFG-GNSS blocks by constellation, with the
+// receiver's default channel allocation.
+var ubxConstellations = map[Constellation]ubxGNSSBlock{
+	ConstellationGPS:     {0, 8, 16},
+	ConstellationGalileo: {2, 4, 8},
+	ConstellationBeiDou:  {3, 8, 16},
+	ConstellationGLONASS: {6, 8, 14},
+}
+
+func ubxCommands(rc ReceiverConfig) (cmds []receiverCmd, err error) {
+	if rc.Rate != 0 {
+		// measRate (ms), navRate (cycles), timeRef (GPS time).
+		p := make([]byte, 6)
+		ms := fixRatePeriods[rc.Rate].Nanoseconds() / 1e6
+		binary.LittleEndian.PutUint16(p[0:], uint16(ms))
+		binary.LittleEndian.PutUint16(p[2:], 1)
+		binary.LittleEndian.PutUint16(p[4:], 1)
+		cmds = append(cmds, ubxCfgCmd("CFG-RATE", ubxCfgRATE, p))
+	}
+	if rc.Sentences != nil {
+		on := make(map[string]bool)
+		for _, s := range rc.Sentences {
+			on[s] = true
+		}
+		for _, s := range ubxSentences {
+			// msgClass, msgID, rate on the current port.
+			p := []byte{ubxClassNMEA, s.id, 0}
+			if on[s.name] {
+				p[2] = 1
+				delete(on, s.name)
+			}
+			cmds = append(cmds, ubxCfgCmd("CFG-MSG "+s.name, ubxCfgMSG, p))
+		}
+		for s := range on {
+			return nil, fmt.Errorf("gps: ubx cannot select sentence %q", s)
+		}
+	}
+	if rc.Constellations != nil {
+		on := make(map[Constellation]bool)
+		for _, c := range rc.Constellations {
+			if _, ok := ubxConstellations[c]; !ok {
+				return nil, fmt.Errorf("gps: ubx cannot select constellation %q", c)
+			}
+			on[c] = true
+		}
+		// msgVer, numTrkChHw, numTrkChUse (all), numConfigBlocks.
+		p := []byte{0, 0, 0xff, byte(len(ubxConstellations))}
+		for _, c := range []Constellation{
+			ConstellationGPS,
+			ConstellationGalileo,
+			ConstellationBeiDou,
+			ConstellationGLONASS,
+		} {
+			b := ubxConstellations[c]
+			// flags: L1 signals, enable.
+			flags := uint32(0x01 << 16)
+			if on[c] {
+				flags |= 0x1
+			}
+			blk := []byte{b.gnssID, b.resTrkCh, b.maxTrkCh, 0, 0, 0, 0, 0}
+			binary.LittleEndian.PutUint32(blk[4:], flags)
+			p = append(p, blk...)
+		}
+		cmds = append(cmds, ubxCfgCmd("CFG-GNSS", ubxCfgGNSS, p))
+	}
+	return cmds, nil
+}
+
+// ubxCfgCmd is a CFG message acknowledged by ACK-ACK or ACK-NAK.
+func ubxCfgCmd(name string, id byte, payload []byte) receiverCmd {
+	return receiverCmd{
+		name: name,
+		data: ubxFrame(ubxClassCFG, id, payload),
+		ack: func(n NMEA) (bool, bool) {
+			f := n.UBX()
+			// ACK payload: clsID, msgID.
+			if len(f) < ubxHeaderLen+4 || f[2] != ubxClassACK {
+				return false, false
+			}
+			if f[6] != ubxClassCFG || f[7] != id {
+				return false, false
+			}
+			return true, f[3] == ubxAckACK
+		},
+	}
+}
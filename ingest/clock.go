@@ -0,0 +1,77 @@
+package ingest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"time"
+)
+
+// ClockStep is a jump of the system clock while recording a trip, as
+// written by the daemon when it sets the clock from GPS.
+type ClockStep struct {
+	// At is the system time when the clock changed, before the change.
+	At time.Time `json:"at"`
+	// Offset is the change in nanoseconds.
+	Offset time.Duration `json:"offset"`
+	// Stepped is unset if the clock slewed instead of jumping, leaving
+	// nothing to correct.
+	Stepped bool `json:"stepped"`
+}
+
+// ReadClockStep reads the clock step of a trip; nil if the clock was
+// left alone.
+func ReadClockStep(tripDir string) (*ClockStep, error) {
+	b, err := ioutil.ReadFile(path.Join(tripDir, "clock.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cs ClockStep
+	if err := json.Unmarshal(b, &cs); err != nil {
+		return nil, err
+	}
+	if !cs.Stepped {
+		return nil, nil
+	}
+	return &cs, nil
+}
+
+// clockFixer corrects the times of a capture that spans a clock step.
+type clockFixer struct {
+	step  *ClockStep
+	after bool
+	last  time.Time
+}
+
+// fix returns the correction for the next time in a capture. Times are
+// in capture order, so the step is where time jumps past At or, for a
+// step back, jumps back.
+func (cf *clockFixer) fix(t time.Time) time.Duration {
+	if cf.step == nil || cf.after {
+		return 0
+	}
+	back := cf.step.Offset < 0 && t.Before(cf.last.Add(cf.step.Offset/2))
+	if t.After(cf.step.At) || back {
+		cf.after = true
+		return 0
+	}
+	cf.last = t
+	return cf.step.Offset
+}
+
+// tripStart is the corrected start time of a trip named by its start;
+// zero if the name is not a time.
+func tripStart(dir, name string) time.Time {
+	t, err := time.Parse(time.RFC3339, name)
+	if err != nil {
+		return time.Time{}
+	}
+	if cs, err := ReadClockStep(path.Join(dir, name)); err == nil && cs != nil {
+		t = t.Add(cs.Offset)
+	}
+	return t
+}
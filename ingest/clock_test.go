@@ -0,0 +1,37 @@
+package ingest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClockFixer(t *testing.T) {
+	at := time.Unix(100, 0)
+	tts := []struct {
+		offset time.Duration
+		times  []int64
+		fixed  []int64
+	}{
+		// Forward from 1970, as on a board without a clock.
+		{
+			1000 * time.Second,
+			[]int64{10, 50, 100, 1101, 1102},
+			[]int64{1010, 1050, 1100, 1101, 1102},
+		},
+		// Back an hour; captured times overlap after the step.
+		{
+			-3600 * time.Second,
+			[]int64{-100, 0, 100, -3499, -3400, 0, 200},
+			[]int64{-3700, -3600, -3500, -3499, -3400, 0, 200},
+		},
+	}
+	for i, tt := range tts {
+		cf := clockFixer{step: &ClockStep{At: at, Offset: tt.offset, Stepped: true}}
+		for j, sec := range tt.times {
+			tm := time.Unix(sec, 0)
+			if got := tm.Add(cf.fix(tm)).Unix(); got != tt.fixed[j] {
+				t.Errorf("#%d.%d: wanted %d, got %d", i, j, tt.fixed[j], got)
+			}
+		}
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"github.com/bikeos/bosd/gps"
 )
 
-func NewGPSChan(dirs []string) (<-chan gps.NMEA, error) {
+// NewGPSChan reads the GPS logs of trips in order. Each trip is
+// filtered separately if filter is set.
+func NewGPSChan(dirs []string, filter *gps.FilterConfig) (<-chan gps.NMEA, error) {
 	ch := make(chan gps.NMEA, 8)
 	go func() {
 		defer close(ch)
@@ -16,7 +18,11 @@ func NewGPSChan(dirs []string) (<-chan gps.NMEA, error) {
 			if err != nil {
 				continue
 			}
-			for v := range g.NMEA() {
+			msgs := g.NMEA()
+			if filter != nil {
+				msgs = gps.FilterNMEA(msgs, *filter)
+			}
+			for v := range msgs {
 				ch <- v
 			}
 			g.Close()
@@ -15,7 +15,7 @@ type GPSPacket struct {
 func (g *GPSPacket) Loc() gps.NMEA    { return g.loc }
 func (g *GPSPacket) Pkt() wlan.Packet { return g.pkt }
 
-func NewGPSPackets(dirs []string) (<-chan GPSPacket, error) {
+func NewGPSPackets(dirs []string, filter *gps.FilterConfig) (<-chan GPSPacket, error) {
 	ifaces, err := Interfaces(dirs)
 	if err != nil {
 		return nil, err
@@ -39,7 +39,7 @@ func NewGPSPackets(dirs []string) (<-chan GPSPacket, error) {
 		ifchs[i] = ch
 	}
 
-	gpsc, err := NewGPSChan(dirs)
+	gpsc, err := NewGPSChan(dirs, filter)
 	if err != nil {
 		closeifchs()
 		return nil, err
@@ -82,7 +82,7 @@ func bufferPkts(ch chan GPSPacket, gpsc <-chan gps.NMEA, ifchs []<-chan wlan.Pac
 		return updated
 	}
 	for n := range gpsc {
-		if n.Fix().IsZero() {
+		if !n.Valid() || n.Fix().IsZero() {
 			continue
 		}
 		if lat := n.Latitude(); lat != lat {
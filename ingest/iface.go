@@ -4,6 +4,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 
 	"github.com/bikeos/bosd/wlan"
@@ -32,12 +33,13 @@ func NewIfacePacketChan(tripDirs []string, iface string) (<-chan wlan.Packet, er
 	go func() {
 		defer close(pktc)
 		for _, name := range tripDirs {
-			d := path.Join(name, "wifi", iface)
-			if _, err := os.Stat(d); err != nil {
-				continue
-			}
-			for pkt := range newPacketDirChan(d) {
-				pktc <- pkt
+			// Captures before the daemon set the clock are off.
+			cs, _ := ReadClockStep(name)
+			cf := clockFixer{step: cs}
+			for _, d := range captureDirs(name, iface) {
+				for pkt := range newPacketDirChan(d) {
+					pktc <- pkt.Shift(cf.fix(pkt.Time()))
+				}
 			}
 		}
 	}()
@@ -50,13 +52,37 @@ func logInterfaces(tripDir string) (ifaces []string, err error) {
 		return nil, err
 	}
 	for _, info := range fi {
-		if strings.HasPrefix(info.Name(), "wl") {
-			ifaces = append(ifaces, info.Name())
+		name := info.Name()
+		if !strings.HasPrefix(name, "wl") {
+			continue
+		}
+		// Restarted captures (e.g., wlan1.1) belong to their device.
+		if i := strings.LastIndexByte(name, '.'); i > 0 {
+			if _, err := strconv.Atoi(name[i+1:]); err == nil {
+				continue
+			}
 		}
+		ifaces = append(ifaces, name)
 	}
 	return ifaces, nil
 }
 
+// captureDirs are the capture directories of an interface in a trip,
+// oldest first. The daemon numbers the directories of restarted
+// captures: wlan1, wlan1.1, wlan1.2, ...
+func captureDirs(tripDir, iface string) (dirs []string) {
+	for i := 0; ; i++ {
+		d := path.Join(tripDir, "wifi", iface)
+		if i > 0 {
+			d += "." + strconv.Itoa(i)
+		}
+		if _, err := os.Stat(d); err != nil {
+			return dirs
+		}
+		dirs = append(dirs, d)
+	}
+}
+
 func newPacketDirChan(ifaceDir string) <-chan wlan.Packet {
 	names, err := pcapSortedNames(ifaceDir)
 	if err != nil {
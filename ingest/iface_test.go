@@ -0,0 +1,40 @@
+package ingest
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestInterfacesMergeRestarts(t *testing.T) {
+	trip, err := ioutil.TempDir("", "trip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(trip)
+	for _, d := range []string{"wlan0", "wlan1", "wlan1.1", "wlan1.2", "wlan2.x"} {
+		if err := os.MkdirAll(path.Join(trip, "wifi", d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ifaces, err := Interfaces([]string{trip})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(ifaces)
+	if want := []string{"wlan0", "wlan1", "wlan2.x"}; !reflect.DeepEqual(ifaces, want) {
+		t.Errorf("wanted interfaces %v, got %v", want, ifaces)
+	}
+	dirs := captureDirs(trip, "wlan1")
+	want := []string{
+		path.Join(trip, "wifi", "wlan1"),
+		path.Join(trip, "wifi", "wlan1.1"),
+		path.Join(trip, "wifi", "wlan1.2"),
+	}
+	if !reflect.DeepEqual(dirs, want) {
+		t.Errorf("wanted dirs %v, got %v", want, dirs)
+	}
+}
@@ -1,21 +1,23 @@
 package ingest
 
+import "github.com/bikeos/bosd/geo"
+
 type LatLon struct {
 	Lat float64
 	Lon float64
 }
 
+// Rect is the area between a north-west (top left) and a south-east
+// (bottom right) corner. A rect with TL east of BR crosses the
+// antimeridian.
 type Rect struct {
 	TL LatLon
 	BR LatLon
 }
 
-func (r *Rect) Contains(ll LatLon) bool {
-	if ll.Lat < r.TL.Lat || ll.Lat > r.BR.Lat {
-		return false
-	}
-	if ll.Lon > r.TL.Lon || ll.Lon < r.BR.Lon {
-		return false
-	}
-	return true
+// Box is the area of the rect.
+func (r *Rect) Box() geo.Box {
+	return geo.Box{South: r.BR.Lat, West: r.TL.Lon, North: r.TL.Lat, East: r.BR.Lon}
 }
+
+func (r *Rect) Contains(ll LatLon) bool { return r.Box().Contains(geo.Point(ll)) }
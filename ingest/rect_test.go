@@ -0,0 +1,30 @@
+package ingest
+
+import "testing"
+
+func TestRectContains(t *testing.T) {
+	tts := []struct {
+		r    Rect
+		ll   LatLon
+		want bool
+	}{
+		// Munich, north of the equator.
+		{Rect{LatLon{48.2, 11.4}, LatLon{48.0, 11.7}}, LatLon{48.1, 11.5}, true},
+		{Rect{LatLon{48.2, 11.4}, LatLon{48.0, 11.7}}, LatLon{47.9, 11.5}, false},
+		{Rect{LatLon{48.2, 11.4}, LatLon{48.0, 11.7}}, LatLon{48.1, 11.8}, false},
+		// Buenos Aires, south of the equator and west of Greenwich.
+		{Rect{LatLon{-34.5, -58.6}, LatLon{-34.7, -58.3}}, LatLon{-34.6, -58.4}, true},
+		{Rect{LatLon{-34.5, -58.6}, LatLon{-34.7, -58.3}}, LatLon{-34.4, -58.4}, false},
+		{Rect{LatLon{-34.5, -58.6}, LatLon{-34.7, -58.3}}, LatLon{-34.6, -58.7}, false},
+		// Fiji, across the antimeridian.
+		{Rect{LatLon{-16, 177}, LatLon{-19, -179}}, LatLon{-17, 179}, true},
+		{Rect{LatLon{-16, 177}, LatLon{-19, -179}}, LatLon{-17, -179.5}, true},
+		{Rect{LatLon{-16, 177}, LatLon{-19, -179}}, LatLon{-17, 0}, false},
+		{Rect{LatLon{-16, 177}, LatLon{-19, -179}}, LatLon{-20, 179}, false},
+	}
+	for i, tt := range tts {
+		if got := tt.r.Contains(tt.ll); got != tt.want {
+			t.Errorf("#%d: %+v contains %+v: wanted %v, got %v", i, tt.r, tt.ll, tt.want, got)
+		}
+	}
+}
@@ -5,17 +5,27 @@ import (
 	"fmt"
 	"os"
 	"path"
+
+	"github.com/bikeos/bosd/gps"
 )
 
+// TimeMap buckets packets by the UnixNano time of their fix.
 type TimeMap map[int64][]GPSPacket
 
+// timeMapVersion is the format of a saved TimeMapDB. Version 1 keys
+// buckets by UnixNano instead of Unix; caches without a version are
+// older.
+const timeMapVersion = 1
+
 type TimeMapDB struct {
+	Version int
 	Trips   map[string]struct{}
 	Packets TimeMap
 }
 
 func NewTimeMapDB() *TimeMapDB {
 	return &TimeMapDB{
+		Version: timeMapVersion,
 		Trips:   make(map[string]struct{}),
 		Packets: make(TimeMap),
 	}
@@ -27,7 +37,15 @@ func (tdb *TimeMapDB) Load(path string) error {
 		return err
 	}
 	defer f.Close()
-	return gob.NewDecoder(f).Decode(tdb)
+	var db TimeMapDB
+	if err := gob.NewDecoder(f).Decode(&db); err != nil {
+		return err
+	}
+	if db.Version != timeMapVersion {
+		return fmt.Errorf("%s: time map version %d, want %d", path, db.Version, timeMapVersion)
+	}
+	*tdb = db
+	return nil
 }
 
 func (tdb *TimeMapDB) Save(path string) error {
@@ -39,12 +57,14 @@ func (tdb *TimeMapDB) Save(path string) error {
 	return gob.NewEncoder(f).Encode(*tdb)
 }
 
-func (tdb *TimeMapDB) AddTrips(dir string) error {
+// AddTrips joins the packets of the trips in a directory with their
+// fixes, filtered if filter is set.
+func (tdb *TimeMapDB) AddTrips(dir string, filter *gps.FilterConfig) error {
 	if len(tdb.Trips) > 0 {
 		// TODO: filter out already processed dirs; load from cache
 		panic("STUB")
 	}
-	trips, err := sortedNames(dir)
+	trips, err := sortedTrips(dir)
 	if err != nil {
 		return err
 	}
@@ -52,7 +72,7 @@ func (tdb *TimeMapDB) AddTrips(dir string) error {
 	for i := range trips {
 		paths[i] = path.Join(dir, trips[i])
 	}
-	ch, err := NewGPSPackets(paths)
+	ch, err := NewGPSPackets(paths, filter)
 	if err != nil {
 		return err
 	}
@@ -70,7 +90,7 @@ func (tdb *TimeMapDB) AddTrips(dir string) error {
 func NewTimeMap(ch <-chan GPSPacket) (TimeMap, error) {
 	timemap, macset := make(TimeMap), make(map[string]struct{})
 	for gpkt := range ch {
-		t := gpkt.Loc().Fix().Unix()
+		t := gpkt.Loc().Fix().UnixNano()
 		if lon := gpkt.Loc().Longitude(); lon != lon {
 			return nil, fmt.Errorf("expected a location")
 		}
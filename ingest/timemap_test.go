@@ -0,0 +1,42 @@
+package ingest
+
+import (
+	"encoding/gob"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTimeMapDBVersion(t *testing.T) {
+	trips := map[string]struct{}{"trip": {}}
+	tts := []struct {
+		db interface{}
+		ok bool
+	}{
+		{struct {
+			Version int
+			Trips   map[string]struct{}
+		}{timeMapVersion, trips}, true},
+		// Caches from before the version have buckets keyed by Unix.
+		{struct{ Trips map[string]struct{} }{trips}, false},
+	}
+	for i, tt := range tts {
+		p := filepath.Join(t.TempDir(), "timemap.gob")
+		f, err := os.Create(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = gob.NewEncoder(f).Encode(tt.db)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		tdb := NewTimeMapDB()
+		if err := tdb.Load(p); (err == nil) != tt.ok {
+			t.Errorf("#%d: wanted ok=%v, got %v", i, tt.ok, err)
+		}
+		if loaded := len(tdb.Trips) == 1; loaded != tt.ok {
+			t.Errorf("#%d: wanted loaded=%v, got trips %v", i, tt.ok, tdb.Trips)
+		}
+	}
+}
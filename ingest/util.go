@@ -5,6 +5,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 func sortedNames(d string) ([]string, error) {
@@ -22,6 +23,24 @@ func sortedNames(d string) ([]string, error) {
 	return names, nil
 }
 
+// sortedTrips sorts the trips in a directory by their start, corrected
+// for clock steps; a board without a clock names trips from 1970 until
+// it sees a fix.
+func sortedTrips(d string) ([]string, error) {
+	names, err := sortedNames(d)
+	if err != nil {
+		return nil, err
+	}
+	starts := make(map[string]time.Time, len(names))
+	for _, name := range names {
+		starts[name] = tripStart(d, name)
+	}
+	sort.SliceStable(names, func(i, j int) bool {
+		return starts[names[i]].Before(starts[names[j]])
+	})
+	return names, nil
+}
+
 func timeSortedNames(d string) ([]string, error) {
 	fi, err := ioutil.ReadDir(d)
 	if err != nil {
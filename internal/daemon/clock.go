@@ -0,0 +1,106 @@
+package daemon
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/bikeos/bosd/gps"
+)
+
+const (
+	// clockAgree is the number of fixes in a row that must keep time
+	// with the system clock before the clock is set.
+	clockAgree = 3
+	// maxSlew is the largest offset slewed instead of stepped.
+	maxSlew = 500 * time.Millisecond
+)
+
+// clockRecord is the clock change made while recording a trip. Times
+// from before it are off by the offset.
+type clockRecord struct {
+	// At is the system time when the clock changed, before the change.
+	At time.Time `json:"at"`
+	// Offset is the change in nanoseconds.
+	Offset time.Duration `json:"offset"`
+	// Stepped is set if the clock jumped instead of slewing.
+	Stepped bool `json:"stepped"`
+}
+
+// clockSync finds the offset of the system clock from GPS fixes.
+type clockSync struct {
+	agree    int
+	lastFix  time.Time
+	lastRecv time.Time
+}
+
+// update takes the time of a fix and the time it was received. Returns
+// the clock offset once enough fixes agree.
+func (c *clockSync) update(fix, recv time.Time) (time.Duration, bool) {
+	if fix.Equal(c.lastFix) {
+		return 0, false
+	}
+	// Compare the time between fixes with the monotonic clock so a
+	// garbled sentence cannot set the clock.
+	if !c.lastFix.IsZero() {
+		if d := fix.Sub(c.lastFix) - recv.Sub(c.lastRecv); d > -time.Second && d < time.Second {
+			c.agree++
+		} else {
+			c.agree = 0
+		}
+	}
+	c.lastFix, c.lastRecv = fix, recv
+	if c.agree+1 < clockAgree {
+		return 0, false
+	}
+	return fix.Sub(recv), true
+}
+
+// startClockSync sets the system clock from the GPS once, recording the
+// change in the trip.
+func (d *daemon) startClockSync() {
+	d.worker("clock", restartBackoff, func(ctx context.Context) error {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		msgs := d.gps.Subscribe(ctx, gps.DropOldest)
+		var cs clockSync
+		for msg := range msgs {
+			fix, recv := msg.Fix(), msg.Received()
+			if !msg.Valid() || fix.IsZero() || recv.IsZero() {
+				continue
+			}
+			if err := gps.CheckFixTime(fix); err != nil {
+				log.Warn(err)
+				continue
+			}
+			if off, ok := cs.update(fix, recv); ok {
+				d.setClock(off)
+				return nil
+			}
+		}
+		return nil
+	})
+}
+
+// setClock slews or steps the system clock by an offset. Errors are
+// logged; the trip keeps the old clock.
+func (d *daemon) setClock(off time.Duration) {
+	rec := clockRecord{At: time.Now(), Offset: off, Stepped: off < -maxSlew || off > maxSlew}
+	var err error
+	if rec.Stepped {
+		tv := unix.NsecToTimeval(rec.At.Add(off).UnixNano())
+		err = unix.Settimeofday(&tv)
+	} else {
+		err = slewClock(off)
+	}
+	if err != nil {
+		log.Errorf("clock: setting by %v: %v", off, err)
+		return
+	}
+	log.Infof("clock: set by %v from GPS", off)
+	if err := d.s.SetClock(rec); err != nil {
+		log.Errorf("clock: %v", err)
+	}
+}
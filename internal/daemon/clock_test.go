@@ -0,0 +1,36 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClockSync(t *testing.T) {
+	fix := time.Date(2018, 3, 12, 2, 55, 3, 0, time.UTC)
+	recv := time.Unix(5, 0)
+	off := fix.Sub(recv)
+	tts := []struct {
+		fix, recv time.Time
+		ok        bool
+	}{
+		{fix, recv, false},
+		// Same fix from another sentence.
+		{fix, recv.Add(50 * time.Millisecond), false},
+		{fix.Add(time.Second), recv.Add(time.Second), false},
+		// A garbled time starts over.
+		{fix.Add(time.Hour), recv.Add(2 * time.Second), false},
+		{fix.Add(3 * time.Second), recv.Add(3 * time.Second), false},
+		{fix.Add(4 * time.Second), recv.Add(4 * time.Second), false},
+		{fix.Add(5 * time.Second), recv.Add(5100 * time.Millisecond), true},
+	}
+	var cs clockSync
+	for i, tt := range tts {
+		v, ok := cs.update(tt.fix, tt.recv)
+		if ok != tt.ok {
+			t.Fatalf("#%d: expected ok %v, got %v", i, tt.ok, ok)
+		}
+		if want := off - 100*time.Millisecond; ok && v != want {
+			t.Errorf("#%d: wanted offset %v, got %v", i, want, v)
+		}
+	}
+}
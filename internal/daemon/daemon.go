@@ -3,16 +3,32 @@ package daemon
 import (
 	"context"
 	"fmt"
-	"io"
 	"sync"
-	"time"
 
-	"github.com/bikeos/bosd/audio"
+	"github.com/bikeos/bosd/gps"
+	"github.com/bikeos/bosd/ntp"
 	log "github.com/sirupsen/logrus"
 )
 
 type Config struct {
 	OutDirPath string
+	// GPSDAddr reads positions from gpsd instead of the GPS devices.
+	GPSDAddr string
+	// NMEAServeAddr rebroadcasts NMEA sentences to clients.
+	NMEAServeAddr string
+	// GPSDServeAddr serves gpsd TPV reports to clients.
+	GPSDServeAddr string
+	// GPSSim rides a synthetic GPS instead of the GPS devices.
+	GPSSim *gps.SimConfig
+	// GPSFilter filters the positions reported while riding; nil
+	// reports raw fixes.
+	GPSFilter *gps.FilterConfig
+	// SetClock sets the system clock from the first GPS fixes.
+	SetClock bool
+	// NTP feeds GPS time to an NTP daemon; nil leaves the clock alone.
+	NTP *ntp.RefclockConfig
+	// Settings are from the config file in the data directory.
+	Settings Settings
 }
 
 type daemon struct {
@@ -22,89 +38,115 @@ type daemon struct {
 
 	s *store
 
+	// st are the settings in effect; stc closes when they change.
+	st   Settings
+	stc  chan struct{}
+	stMu sync.RWMutex
+
 	gs   gpsStatus
+	sky  skyStatus
 	gsMu sync.RWMutex
-}
 
-var reportInterval = 20 * time.Second
+	// workers are the supervision records by worker name.
+	workers map[string]*workerStatus
+	wMu     sync.Mutex
+
+	// sel picks the best of the receivers in rxs for gps. Cancelling a
+	// receiver's context stops reading it.
+	sel     *gps.Selector
+	gps     *gps.Broadcaster
+	rxs     map[string]context.CancelFunc
+	rxMu    sync.Mutex
+	nmeaSrv *gpsServer
+	gpsdSrv *gpsServer
+
+	wm *wifiMon
+}
 
 func Run(cfg Config) error {
 	d := &daemon{
 		cfg: cfg,
 		ctx: newDaemonCtx(),
+		rxs: make(map[string]context.CancelFunc),
 	}
+	d.setSettings(cfg.Settings.withDefaults())
 	return d.run()
 }
 
 func (d *daemon) run() (err error) {
-	defer func() {
-		d.ctx.Cancel(io.EOF)
-		d.wg.Wait()
-	}()
+	defer d.shutdown()
 	if d.s, err = newStore(d.cfg.OutDirPath); err != nil {
 		return err
 	}
 	if err = d.startGPS(); err != nil {
 		return err
 	}
+	if err = d.startGPSServers(); err != nil {
+		return err
+	}
 	if err = d.startWifi(); err != nil {
 		return err
 	}
+	d.worker("signals", restartFatal, d.watchSignals)
+	d.worker("notify", restartBackoff, d.notify)
 	if err = d.startAudio(); err != nil {
 		log.Errorf("audio: %v", err)
 		return err
 	}
 
-	repc, err := NewReportChannel(d, d.ctx.ctx)
+	repc, err := NewReportChannel(d)
 	if err != nil {
 		return err
 	}
 
 	updatec := make(chan struct{}, 1)
-	inputc, err := NewInputChannel(d.ctx.ctx)
+	st, _ := d.settings()
+	inputc, err := NewInputChannel(d.ctx.ctx, st.Gamepad)
 	if err != nil {
-		d.worker(func(ctx context.Context) error {
+		d.worker("report/timer", restartFatal, func(ctx context.Context) error {
 			for {
 				select {
 				case updatec <- struct{}{}:
 				case <-ctx.Done():
 					return nil
 				}
-				select {
-				case <-time.After(reportInterval):
-				case <-ctx.Done():
+				if !d.wait(ctx, func(st Settings) Duration { return st.ReportInterval }) {
 					return nil
 				}
 			}
 		})
 	} else {
 		log.Infof("gamepad input detected")
-		d.worker(func(ctx context.Context) error {
-			for {
-				select {
-				case _, ok := <-inputc:
-					if !ok {
-						return io.EOF
-					}
-				case <-ctx.Done():
-					return nil
+		d.worker("report/input", restartBackoff, func(ctx context.Context) error {
+			// Reopen the gamepad if it was lost.
+			if inputc == nil {
+				c, err := NewInputChannel(ctx, st.Gamepad)
+				if err != nil {
+					return err
 				}
+				inputc = c
+			}
+			for range inputc {
 				select {
 				case updatec <- struct{}{}:
 				case <-ctx.Done():
-					return nil
 				}
 			}
+			inputc = nil
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("gamepad %q lost", st.Gamepad)
 		})
 	}
-	d.worker(func(ctx context.Context) error {
+	d.worker("report/say", restartBackoff, func(ctx context.Context) error {
 		for say := range repc {
 			select {
 			case <-updatec:
 			case <-ctx.Done():
 				return nil
 			}
-			if err := audio.Say(ctx, say); err != nil {
+			if err := d.player().Say(ctx, say); err != nil {
 				return err
 			}
 		}
@@ -112,14 +154,3 @@ func (d *daemon) run() (err error) {
 	})
 	return <-d.ctx.errc
 }
-
-func (d *daemon) worker(f func(ctx context.Context) error) {
-	d.wg.Add(1)
-	go func() {
-		defer d.wg.Done()
-		if err := f(d.ctx.ctx); err != nil {
-			audio.Say(d.ctx.ctx, fmt.Sprintf("%v", err))
-			d.ctx.Cancel(err)
-		}
-	}()
-}
@@ -2,13 +2,16 @@ package daemon
 
 import (
 	"context"
-	"fmt"
 	"io"
+	"os"
+	"path/filepath"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/bikeos/bosd/geo"
 	"github.com/bikeos/bosd/gps"
+	"github.com/bikeos/bosd/ntp"
 )
 
 type gpsStatus struct {
@@ -17,63 +20,248 @@ type gpsStatus struct {
 	lon  float64
 }
 
+func (gs gpsStatus) point() geo.Point { return geo.Point{Lat: gs.lat, Lon: gs.lon} }
+
+type skyStatus struct {
+	when time.Time
+	sky  gps.Sky
+}
+
+// gpsProfile is the receiver profile among the config files.
+const gpsProfile = "gps.json"
+
+// gpsScanTime is how often to look for plugged in receivers.
+var gpsScanTime = 5 * time.Second
+
+// startGPS reads every receiver, sharing the best one's stream with
+// other workers.
 func (d *daemon) startGPS() error {
-	// TODO: GPS hotplug
-	gs, err := gps.Enumerate()
-	if err != nil {
-		return err
+	d.sel = gps.NewSelector(gps.SelectorConfig{OnSwitch: func(from, to string) {
+		if to == "" {
+			log.Warnf("gps: no receivers left")
+			return
+		}
+		log.Infof("gps: using %q", to)
+	}})
+	d.gps = gps.NewChanBroadcaster(d.sel.NMEA())
+	d.worker("gps/log", restartBackoff, func(ctx context.Context) error {
+		return d.gpsLogger(d.gps.Subscribe(ctx, gps.Block), d.s.GPS())
+	})
+	if d.cfg.SetClock {
+		d.startClockSync()
+	}
+	if d.cfg.NTP != nil {
+		d.startRefclock()
+	}
+	switch {
+	case d.cfg.GPSDAddr != "":
+		log.Infof("reading from gpsd %q", d.cfg.GPSDAddr)
+		d.addReceiver("gpsd", restartBackoff, func() (*gps.GPS, error) {
+			return gps.NewGPSD(d.cfg.GPSDAddr)
+		})
+	case d.cfg.GPSSim != nil:
+		log.Infof("reading from simulated GPS")
+		d.externalWorker("gps/sim", restartFatal, d.simGPS)
+	default:
+		d.worker("gps/watch", restartBackoff, d.watchGPS)
 	}
-	var gg *gps.GPS
-	for _, g := range gs {
-		if gg, err = gps.NewGPS(g); err == nil {
-			log.Infof("reading from GPS %q", g)
-			break
+	return nil
+}
+
+// watchGPS reads allowed receivers as they are plugged in, and stops
+// reading receivers taken off the allow-list.
+func (d *daemon) watchGPS(ctx context.Context) error {
+	for {
+		st, changed := d.settings()
+		d.rxMu.Lock()
+		for name, cancel := range d.rxs {
+			if !allowed(st.GPSDevices, name) {
+				log.Infof("gps %q not allowed; closing", name)
+				cancel()
+			}
+		}
+		d.rxMu.Unlock()
+		devs, err := gps.Enumerate()
+		if err != nil {
+			return err
 		}
+		for _, dev := range devs {
+			dev, name := dev, filepath.Base(dev)
+			if d.hasReceiver(name) || !allowed(st.GPSDevices, name) {
+				continue
+			}
+			log.Infof("reading from GPS %q", dev)
+			d.addReceiver(name, restartBackoff, func() (*gps.GPS, error) {
+				d.configureGPS(dev)
+				return gps.NewGPS(dev)
+			})
+		}
+		select {
+		case <-time.After(gpsScanTime):
+		case <-changed:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (d *daemon) hasReceiver(name string) bool {
+	d.rxMu.Lock()
+	defer d.rxMu.Unlock()
+	_, ok := d.rxs[name]
+	return ok
+}
+
+// addReceiver logs a receiver and offers it to the selector, reopening
+// it after failures. A receiver that is unplugged or taken off the
+// allow-list is done; watchGPS adds it again if it comes back.
+func (d *daemon) addReceiver(name string, p restartPolicy, open func() (*gps.GPS, error)) {
+	ctx, cancel := context.WithCancel(d.ctx.ctx)
+	d.rxMu.Lock()
+	d.rxs[name] = cancel
+	d.rxMu.Unlock()
+	done := func() {
+		cancel()
+		d.rxMu.Lock()
+		delete(d.rxs, name)
+		d.rxMu.Unlock()
 	}
+	d.externalWorker("gps/"+name, p, func(context.Context) error {
+		g, err := open()
+		if os.IsNotExist(err) || ctx.Err() != nil {
+			if g != nil {
+				g.Close()
+			}
+			done()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err = d.readReceiver(ctx, name, g); err == nil {
+			done()
+		}
+		return err
+	})
+}
+
+// simGPS rides the simulated route. The ride ends with the route, so
+// the daemon stops cleanly once it is done.
+func (d *daemon) simGPS(ctx context.Context) error {
+	g, err := gps.NewSimGPS(*d.cfg.GPSSim)
 	if err != nil {
 		return err
 	}
-	if gg == nil {
-		return fmt.Errorf("gps: no gps found")
+	if err := d.readReceiver(ctx, "sim", g); err != io.EOF {
+		return err
 	}
-	d.worker(func(ctx context.Context) error {
-		return d.gpsLogger(gg, d.s)
-	})
+	log.Infof("gps: simulated route done")
+	d.ctx.Cancel(nil)
 	return nil
 }
 
-func (d *daemon) gpsLogger(g *gps.GPS, s *store) (err error) {
+// readReceiver reads a receiver until its stream ends or ctx is done.
+func (d *daemon) readReceiver(ctx context.Context, name string, g *gps.GPS) (err error) {
+	logs := d.s.Receiver(name)
+	donec := make(chan struct{})
 	defer func() {
-		if cerr := g.Close(); err == nil {
+		close(donec)
+		d.sel.Remove(name)
+		if cerr := g.Close(); err == nil && ctx.Err() == nil {
+			err = cerr
+		}
+		if cerr := logs.Close(); err == nil {
 			err = cerr
 		}
+		log.Infof("gps %q: %+v", name, g.Stats())
 	}()
-	w, err := s.GPS()
+	go func() {
+		select {
+		case <-ctx.Done():
+			g.Close()
+		case <-donec:
+		}
+	}()
+	for msg := range g.NMEA() {
+		if err := logs.Write(msg); err != nil {
+			return err
+		}
+		d.sel.Update(name, msg)
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return io.EOF
+}
+
+// startRefclock feeds GPS time to the NTP daemon. Samples carry the time
+// each message was read, so a lagging subscription costs nothing.
+func (d *daemon) startRefclock() {
+	d.externalWorker("ntp", restartBackoff, func(ctx context.Context) error {
+		rc, err := ntp.NewRefclock(*d.cfg.NTP)
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		log.Infof("ntp: feeding GPS time to SHM unit %d", rc.Unit())
+		for msg := range d.gps.Subscribe(ctx, gps.DropOldest) {
+			rc.Update(msg)
+		}
+		return nil
+	})
+}
+
+// configureGPS applies the receiver profile, if any, to a device.
+func (d *daemon) configureGPS(dev string) {
+	p := d.s.ConfigPath(gpsProfile)
+	rc, err := gps.ReadProfile(p)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err == nil {
+		err = gps.ConfigureDevice(dev, gps.Config{}, rc)
+	}
 	if err != nil {
-		return err
+		log.Errorf("gps: configuring %q: %v", dev, err)
+		return
 	}
-	for {
-		select {
-		case msg, ok := <-g.NMEA():
-			if !ok {
-				return io.EOF
-			}
-			if !msg.Fix().IsZero() {
-				lat, lon := msg.Latitude(), msg.Longitude()
-				if lat != lat {
-					continue
-				}
-				newStatus := gpsStatus{time.Now(), lat, lon}
-				d.gsMu.Lock()
-				d.gs = newStatus
-				d.gsMu.Unlock()
-			}
-			l := []byte(msg.Line())
-			if _, err := w.Write(l); err != nil {
-				return err
-			}
-		case <-d.ctx.Done():
-			return nil
+	log.Infof("gps: configured %q with %q", dev, p)
+}
+
+// gpsPosition is the position of a fix, filtered if there is a filter.
+func gpsPosition(filter *gps.Filter, msg gps.NMEA) (gps.Fix, bool) {
+	if filter != nil {
+		return filter.Update(msg)
+	}
+	if !msg.Valid() || msg.Fix().IsZero() || msg.Latitude() != msg.Latitude() {
+		return gps.Fix{}, false
+	}
+	return gps.NewFix(msg), true
+}
+
+// gpsLogger logs the selected GPS stream and tracks its position and
+// sky view for reports.
+func (d *daemon) gpsLogger(msgs <-chan gps.NMEA, logs *gpsLogs) error {
+	sv := gps.NewSkyView()
+	var filter *gps.Filter
+	if d.cfg.GPSFilter != nil {
+		filter = gps.NewFilter(*d.cfg.GPSFilter)
+	}
+	for msg := range msgs {
+		if pos, ok := gpsPosition(filter, msg); ok {
+			newStatus := gpsStatus{time.Now(), pos.Latitude, pos.Longitude}
+			d.gsMu.Lock()
+			d.gs = newStatus
+			d.gsMu.Unlock()
+		}
+		if sv.Update(msg) {
+			newSky := skyStatus{time.Now(), sv.Sky()}
+			d.gsMu.Lock()
+			d.sky = newSky
+			d.gsMu.Unlock()
+		}
+		if err := logs.Write(msg); err != nil {
+			return err
 		}
 	}
 	return nil
@@ -18,8 +18,9 @@ const (
 	InputRight
 )
 
-func NewInputChannel(ctx context.Context) (<-chan InputEvent, error) {
-	dev, err := openGamepad()
+// NewInputChannel reads joystick events from the named gamepad.
+func NewInputChannel(ctx context.Context, gamepad string) (<-chan InputEvent, error) {
+	dev, err := openGamepad(gamepad)
 	if err != nil {
 		return nil, err
 	}
@@ -76,10 +77,10 @@ func NewInputChannel(ctx context.Context) (<-chan InputEvent, error) {
 	return ch, nil
 }
 
-func openGamepad() (*evdev.InputDevice, error) {
+func openGamepad(name string) (*evdev.InputDevice, error) {
 	devices, _ := evdev.ListInputDevices()
 	for _, dev := range devices {
-		if strings.TrimSpace(dev.Name) == "usb gamepad" {
+		if strings.TrimSpace(dev.Name) == name {
 			return evdev.Open(dev.Fn)
 		}
 	}
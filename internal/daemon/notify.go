@@ -0,0 +1,140 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bikeos/bosd/systemd"
+)
+
+// notifyTime is how often to look for changes to tell systemd.
+var notifyTime = time.Second
+
+// notifyFixAge is how old the last position can be to count as a fix.
+const notifyFixAge = 10 * time.Second
+
+// unhealthyFailures is how many failures in a row of an internal worker
+// make the daemon unhealthy.
+const unhealthyFailures = 3
+
+// notifyState is what the daemon tells systemd about.
+type notifyState struct {
+	store bool
+	gps   gpsStatus
+	sky   skyStatus
+	// source is the selected receiver; empty until one reports.
+	source    string
+	receivers int
+	captures  int
+	dead      []string
+}
+
+// ready is set once the store, the GPS, and a capture are up.
+func (ns notifyState) ready() bool {
+	return ns.store && ns.source != "" && ns.captures > 0
+}
+
+func (ns notifyState) status(now time.Time) string {
+	var fix string
+	switch {
+	case !ns.gps.when.IsZero() && now.Sub(ns.gps.when) <= notifyFixAge:
+		fix = fmt.Sprintf("GPS fix with %d satellites", ns.sky.sky.Active())
+	case ns.source != "":
+		fix = fmt.Sprintf("GPS searching, tracking %d satellites", ns.sky.sky.Tracked())
+	default:
+		fix = "no GPS"
+	}
+	s := fmt.Sprintf("%s; receivers: %d; captures: %d", fix, ns.receivers, ns.captures)
+	if len(ns.dead) > 0 {
+		s += "; dead: " + strings.Join(ns.dead, " ")
+	}
+	return s
+}
+
+func (d *daemon) notifyState() notifyState {
+	var ns notifyState
+	ns.store = d.s != nil
+	d.gsMu.RLock()
+	ns.gps, ns.sky = d.gs, d.sky
+	d.gsMu.RUnlock()
+	if d.sel != nil {
+		ns.source = d.sel.Source()
+	}
+	d.rxMu.Lock()
+	ns.receivers = len(d.rxs)
+	d.rxMu.Unlock()
+	if d.wm != nil {
+		ns.captures, ns.dead = d.wm.captures(), d.wm.deadDevs()
+	}
+	return ns
+}
+
+// unhealthy is the first internal worker that keeps failing; empty if
+// none. External workers are left to their restart policies and the
+// capture watchdogs.
+func unhealthy(wss []workerStatus) string {
+	for _, ws := range wss {
+		if !ws.External && ws.Failures >= unhealthyFailures {
+			return ws.Name
+		}
+	}
+	return ""
+}
+
+// notify tells systemd when the daemon is ready and how it is doing,
+// and pings its watchdog while the workers are healthy. A daemon wedged
+// on one of its locks stops pinging too. Done if not run by systemd.
+func (d *daemon) notify(ctx context.Context) error {
+	if ok, err := systemd.Notify("STATUS=starting"); !ok {
+		return err
+	}
+	wdt, err := systemd.WatchdogInterval()
+	if err != nil {
+		log.Errorf("notify: %v", err)
+	}
+	var (
+		ready    bool
+		status   string
+		sick     string
+		lastPing time.Time
+	)
+	for {
+		var msgs []string
+		ns := d.notifyState()
+		if !ready && ns.ready() {
+			log.Infof("notify: ready")
+			msgs, ready = append(msgs, "READY=1"), true
+		}
+		if s := ns.status(time.Now()); s != status {
+			msgs, status = append(msgs, "STATUS="+s), s
+		}
+		if wdt > 0 && time.Since(lastPing) >= wdt/2 {
+			name := unhealthy(d.workerStatuses())
+			if name != sick {
+				if name != "" {
+					log.Warnf("notify: %s keeps failing; stopping watchdog pings", name)
+				} else {
+					log.Infof("notify: healthy again; pinging watchdog")
+				}
+				sick = name
+			}
+			if sick == "" {
+				msgs, lastPing = append(msgs, "WATCHDOG=1"), time.Now()
+			}
+		}
+		if len(msgs) > 0 {
+			if _, err := systemd.Notify(strings.Join(msgs, "\n")); err != nil {
+				return err
+			}
+		}
+		select {
+		case <-time.After(notifyTime):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package daemon
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNotifyState(t *testing.T) {
+	now := time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC)
+	var tts = []struct {
+		ns notifyState
+
+		ready  bool
+		status string
+	}{
+		{notifyState{store: true}, false, "no GPS; receivers: 0; captures: 0"},
+		{notifyState{store: true, source: "ttyACM0", receivers: 1}, false, "GPS searching, tracking 0 satellites; receivers: 1; captures: 0"},
+		{notifyState{store: true, source: "ttyACM0", receivers: 1, captures: 2}, true, "GPS searching, tracking 0 satellites; receivers: 1; captures: 2"},
+		{notifyState{store: true, gps: gpsStatus{when: now.Add(-time.Second)}, source: "ttyACM0", receivers: 2, captures: 1}, true, "GPS fix with 0 satellites; receivers: 2; captures: 1"},
+		// An old fix is no fix.
+		{notifyState{store: true, gps: gpsStatus{when: now.Add(-time.Minute)}, receivers: 1, captures: 1, dead: []string{"wlan2", "wlan3"}}, false, "no GPS; receivers: 1; captures: 1; dead: wlan2 wlan3"},
+		{notifyState{source: "ttyACM0", captures: 1}, false, "GPS searching, tracking 0 satellites; receivers: 0; captures: 1"},
+	}
+	for i, tt := range tts {
+		if got := tt.ns.ready(); got != tt.ready {
+			t.Errorf("%d: got ready %v, want %v", i, got, tt.ready)
+		}
+		if got := tt.ns.status(now); got != tt.status {
+			t.Errorf("%d: got status %q, want %q", i, got, tt.status)
+		}
+	}
+}
+
+func TestUnhealthy(t *testing.T) {
+	var tts = []struct {
+		wss  []workerStatus
+		want string
+	}{
+		{nil, ""},
+		{[]workerStatus{{Name: "gps/log", Failures: 2}, {Name: "report", Running: true}}, ""},
+		{[]workerStatus{{Name: "gps/log", Failures: 3}}, "gps/log"},
+		// A receiver losing its cable is not the daemon's fault.
+		{[]workerStatus{{Name: "gps/ttyACM0", External: true, Failures: 10}, {Name: "wifi", Failures: 1}}, ""},
+	}
+	for i, tt := range tts {
+		if got := unhealthy(tt.wss); got != tt.want {
+			t.Errorf("%d: got %q, want %q", i, got, tt.want)
+		}
+	}
+}
+
+func TestNotify(t *testing.T) {
+	defer func(d time.Duration) { notifyTime = d }(notifyTime)
+	notifyTime = time.Millisecond
+	sock := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: sock, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	t.Setenv("NOTIFY_SOCKET", sock)
+	t.Setenv("WATCHDOG_USEC", "2000")
+	t.Setenv("WATCHDOG_PID", "")
+
+	d := &daemon{
+		ctx:     newDaemonCtx(),
+		rxs:     map[string]context.CancelFunc{"ttyACM0": func() {}},
+		workers: map[string]*workerStatus{"gps/log": {Name: "gps/log"}},
+	}
+	d.setSettings(Settings{}.withDefaults())
+	donec := make(chan error, 1)
+	go func() { donec <- d.notify(d.ctx.ctx) }()
+	defer func() {
+		d.ctx.Cancel(nil)
+		if err := <-donec; err != nil {
+			t.Error(err)
+		}
+	}()
+
+	// read gathers messages until one has the state.
+	read := func(state string) []string {
+		var got []string
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		b := make([]byte, 1024)
+		for {
+			n, err := conn.Read(b)
+			if err != nil {
+				t.Fatalf("waiting for %q: %v; got %q", state, err, got)
+			}
+			got = append(got, strings.Split(string(b[:n]), "\n")...)
+			for _, s := range got {
+				if s == state {
+					return got
+				}
+			}
+		}
+	}
+	got := read("STATUS=no GPS; receivers: 1; captures: 0")
+	got = append(got, read("WATCHDOG=1")...)
+	for _, s := range got {
+		if s == "READY=1" {
+			t.Errorf("ready without GPS or captures: %q", got)
+		}
+	}
+
+	// A failing internal worker stops the pings; drop those sent before
+	// the notifier saw it.
+	d.updateWorker("gps/log", func(ws *workerStatus) { ws.Failures = unhealthyFailures })
+	time.Sleep(20 * time.Millisecond)
+	b := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+	for {
+		if _, err := conn.Read(b); err != nil {
+			break
+		}
+	}
+	conn.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	if n, err := conn.Read(b); err == nil {
+		t.Errorf("unhealthy: got %q", b[:n])
+	}
+}
@@ -0,0 +1,87 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bikeos/bosd/audio"
+)
+
+// settings are the settings in effect and a channel closed when they
+// next change.
+func (d *daemon) settings() (Settings, <-chan struct{}) {
+	d.stMu.RLock()
+	defer d.stMu.RUnlock()
+	return d.st, d.stc
+}
+
+// setSettings puts settings in effect, waking workers waiting on them.
+func (d *daemon) setSettings(st Settings) {
+	d.stMu.Lock()
+	defer d.stMu.Unlock()
+	d.st = st
+	if d.stc != nil {
+		close(d.stc)
+	}
+	d.stc = make(chan struct{})
+}
+
+// player plays sounds with the programs in the settings.
+func (d *daemon) player() *audio.Audio {
+	st, _ := d.settings()
+	return audio.New(audio.Config{MPG321: st.MPG321, Flite: st.Flite})
+}
+
+// wait sleeps for a duration from the settings, counting time already
+// waited if the settings change. Returns false if ctx is done first.
+func (d *daemon) wait(ctx context.Context, dur func(Settings) Duration) bool {
+	start := time.Now()
+	for {
+		st, changed := d.settings()
+		t := time.NewTimer(time.Until(start.Add(time.Duration(dur(st)))))
+		select {
+		case <-t.C:
+			return true
+		case <-changed:
+			t.Stop()
+		case <-ctx.Done():
+			t.Stop()
+			return false
+		}
+	}
+}
+
+// reload puts the config file's settings in effect. A bad file changes
+// nothing.
+func (d *daemon) reload() {
+	next, err := ReadSettings(d.cfg.OutDirPath)
+	if err != nil {
+		log.Errorf("reload: %v", err)
+		return
+	}
+	cur, _ := d.settings()
+	st, errs := reloadSettings(cur, next)
+	for _, err := range errs {
+		log.Warnf("reload: %v", err)
+	}
+	d.setSettings(st)
+	log.Infof("reload: settings %+v", st)
+}
+
+// reloadSettings takes the new settings that can change while running,
+// keeping the current value of the rest.
+func reloadSettings(cur, next Settings) (Settings, []error) {
+	var errs []error
+	if next.PCapSize != cur.PCapSize {
+		errs = append(errs, fmt.Errorf("pcap_size: captures are running; restart to change"))
+		next.PCapSize = cur.PCapSize
+	}
+	if next.Gamepad != cur.Gamepad {
+		errs = append(errs, fmt.Errorf("gamepad: input is open; restart to change"))
+		next.Gamepad = cur.Gamepad
+	}
+	return next, errs
+}
@@ -0,0 +1,110 @@
+package daemon
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReloadSettings(t *testing.T) {
+	cur := Settings{}.withDefaults()
+	var tts = []struct {
+		next func(*Settings)
+
+		want func(*Settings)
+		errs int
+	}{
+		{
+			func(st *Settings) {
+				st.ReportInterval = Duration(time.Minute)
+				st.WifiHop = Duration(time.Second)
+				st.Flite = "/opt/flite"
+				st.GPSDevices = []string{"ttyACM1"}
+			},
+			func(st *Settings) {
+				st.ReportInterval = Duration(time.Minute)
+				st.WifiHop = Duration(time.Second)
+				st.Flite = "/opt/flite"
+				st.GPSDevices = []string{"ttyACM1"}
+			},
+			0,
+		},
+		{
+			// Only the settings that can change live take effect.
+			func(st *Settings) {
+				st.PCapSize = 8
+				st.Gamepad = "pad"
+				st.WifiScan = Duration(time.Minute)
+			},
+			func(st *Settings) {
+				st.WifiScan = Duration(time.Minute)
+			},
+			2,
+		},
+	}
+	for i, tt := range tts {
+		next, want := cur, cur
+		tt.next(&next)
+		tt.want(&want)
+		got, errs := reloadSettings(cur, next)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%d: got %+v, want %+v", i, got, want)
+		}
+		if len(errs) != tt.errs {
+			t.Errorf("%d: got errors %v, want %d", i, errs, tt.errs)
+		}
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	d := &daemon{cfg: Config{OutDirPath: dir}}
+	d.setSettings(Settings{}.withDefaults())
+	_, changed := d.settings()
+
+	// A bad file changes nothing.
+	p := filepath.Join(dir, SettingsFile)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(p, []byte(`{"wifi_hop": "-1s"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	d.reload()
+	select {
+	case <-changed:
+		t.Fatal("bad file changed settings")
+	default:
+	}
+
+	if err := ioutil.WriteFile(p, []byte(`{"report_interval": "1h"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A waiter picks up the shorter interval.
+	waitc := make(chan bool, 1)
+	go func() {
+		waitc <- d.wait(context.Background(), func(st Settings) Duration {
+			if st.ReportInterval == Duration(time.Hour) {
+				return 0
+			}
+			return Duration(time.Hour)
+		})
+	}()
+	d.reload()
+	<-changed
+	if st, _ := d.settings(); st.ReportInterval != Duration(time.Hour) {
+		t.Fatalf("got report interval %v, want 1h", time.Duration(st.ReportInterval))
+	}
+	select {
+	case ok := <-waitc:
+		if !ok {
+			t.Fatal("wait failed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("wait did not see new settings")
+	}
+}
@@ -3,13 +3,15 @@ package daemon
 import (
 	"context"
 	"fmt"
-	"math"
 	"os/exec"
 	"strings"
 
+	"github.com/bikeos/bosd/geo"
 	"github.com/bikeos/bosd/wlan"
 )
 
+const metersPerMile = 1609.344
+
 type report struct {
 	d  *daemon
 	ch chan string
@@ -17,14 +19,15 @@ type report struct {
 	devs        map[string]devmacs
 	macs        map[string]struct{}
 	devsOrdered []string
-	dist        float64 // sum of haversines
+	dist        float64 // meters travelled
 	firstGPS    gpsStatus
 	lastGPS     gpsStatus
+	lastSky     skyStatus
 }
 
 type devmacs map[string]struct{}
 
-func NewReportChannel(d *daemon, ctx context.Context) (<-chan string, error) {
+func NewReportChannel(d *daemon) (<-chan string, error) {
 	r := report{
 		d:    d,
 		devs: make(map[string]devmacs),
@@ -39,10 +42,11 @@ func NewReportChannel(d *daemon, ctx context.Context) (<-chan string, error) {
 		r.devs[wdev.Name()] = make(devmacs)
 		r.devsOrdered = append(r.devsOrdered, wdev.Name())
 	}
-	go func() {
+	d.worker("report", restartFatal, func(ctx context.Context) error {
 		defer close(r.ch)
 		r.run(ctx)
-	}()
+		return nil
+	})
 	return r.ch, nil
 }
 
@@ -67,22 +71,26 @@ func (r *report) makeReport() string {
 
 	r.scanPCaps()
 
+	var dead []string
+	if r.d.wm != nil {
+		dead = r.d.wm.deadDevs()
+	}
 	stuck := 0
 	for _, dev := range r.devsOrdered {
 		macs := len(r.devs[dev])
-		if newMacs := macs - olddevmacs[dev]; newMacs == 0 {
+		if newMacs := macs - olddevmacs[dev]; newMacs == 0 && !contains(dead, dev) {
 			stuck++
 		}
 	}
 
 	r.d.gsMu.RLock()
-	curGPS := r.d.gs
+	curGPS, curSky := r.d.gs, r.d.sky
 	r.d.gsMu.RUnlock()
 	if r.firstGPS.when.IsZero() {
 		r.firstGPS = curGPS
 	}
-	sayStr := r.toString(curGPS, len(r.macs)-oldtotal, stuck)
-	r.lastGPS = curGPS
+	sayStr := r.toString(curGPS, curSky, len(r.macs)-oldtotal, stuck, dead)
+	r.lastGPS, r.lastSky = curGPS, curSky
 	return sayStr
 }
 
@@ -106,60 +114,40 @@ func (r *report) scanPCaps() {
 	}
 }
 
-func deg2rad(deg float64) float64 { return 2 * math.Pi * deg / 360.0 }
-
-func haversine(last, cur gpsStatus) float64 {
-	latDelta := cur.lat - last.lat
-	lonDelta := cur.lon - last.lon
-	phi1, phi2 := deg2rad(last.lat), deg2rad(cur.lat)
-	delPhi, delLambda := deg2rad(latDelta), deg2rad(lonDelta)
-	a := math.Sin(delPhi/2.0)*math.Sin(delPhi/2.0) +
-		math.Cos(phi1)*math.Cos(phi2)*
-			math.Sin(delLambda/2.0)*math.Sin(delLambda/2.0)
-	return 2.0 * math.Atan2(math.Sqrt(a), math.Sqrt(1.0-a))
-}
-
-func delta2bearing(lonDelta, latDelta float64) string {
-	say := ""
-	atanAbs := math.Abs(math.Atan(lonDelta / latDelta))
-	if atanAbs > math.Pi/8.0 {
-		// angle somewhat above y-axis
-		if lonDelta > 0 {
-			say += "north "
-		} else {
-			say += "south "
-		}
-	}
-	if atanAbs < math.Pi/2.0-math.Pi/8.0 {
-		// angle somewhat close to x-axis
-		if latDelta > 0 {
-			say += "west "
-		} else {
-			say += "east "
-		}
-	}
-	return say
-}
-
-func (r *report) toString(curGPS gpsStatus, gained int, stuck int) string {
+func (r *report) toString(curGPS gpsStatus, curSky skyStatus, gained int, stuck int, dead []string) string {
 	say := "radio report: "
 	say += fmt.Sprintf("total unique macs: %d. gained %d.\n", len(r.macs), gained)
 	if stuck != 0 {
 		say += fmt.Sprintf("devices stuck!\n")
 	}
-	if r.lastGPS.when.IsZero() {
-		return say
+	for _, dev := range dead {
+		say += fmt.Sprintf("%s dead.\n", dev)
 	}
 	if curGPS.when.IsZero() || curGPS.when == r.lastGPS.when {
-		say += "GPS stuck."
+		say += r.skyString(curSky)
+	} else if r.lastGPS.when.IsZero() {
+		return say
 	} else if r.lastGPS.lat == r.lastGPS.lat {
-		Rmi := 3959.0
-		hs := haversine(r.lastGPS, curGPS)
-		r.dist += hs
-		say += fmt.Sprintf(". travelled: %.3g miles.", Rmi*r.dist)
+		r.dist += geo.Distance(r.lastGPS.point(), curGPS.point())
+		say += fmt.Sprintf(". travelled: %.3g miles.", r.dist/metersPerMile)
 
-		hs = haversine(r.firstGPS, curGPS)
-		say += fmt.Sprintf(". home: %.3g miles.", Rmi*hs)
+		home := geo.Distance(curGPS.point(), r.firstGPS.point())
+		say += fmt.Sprintf(". home: %.3g miles", home/metersPerMile)
+		if home > 0 {
+			say += " " + geo.Compass(geo.Bearing(curGPS.point(), r.firstGPS.point()))
+		}
+		say += "."
 	}
 	return say
 }
+
+// skyString tells a receiver without a sky view apart from a dead one.
+func (r *report) skyString(curSky skyStatus) string {
+	if curSky.when.IsZero() || curSky.when == r.lastSky.when {
+		return "GPS stuck."
+	}
+	if tracked := curSky.sky.Tracked(); tracked > 0 {
+		return fmt.Sprintf("GPS searching. tracking %d satellites.", tracked)
+	}
+	return "GPS has no sky view."
+}
@@ -2,10 +2,11 @@ package daemon
 
 import (
 	"testing"
+
+	"github.com/bikeos/bosd/geo"
 )
 
-func TestHaversine(t *testing.T) {
-	Rmeter := 6371e3
+func TestDistance(t *testing.T) {
 	var tts = []struct {
 		last gpsStatus
 		cur  gpsStatus
@@ -30,7 +31,7 @@ func TestHaversine(t *testing.T) {
 		},
 	}
 	for i, tt := range tts {
-		v := Rmeter * haversine(tt.last, tt.cur)
+		v := geo.Distance(tt.last.point(), tt.cur.point())
 		if int64(v) != int64(tt.want) {
 			t.Errorf("#%d: wanted %g, got %g", i, tt.want, v)
 		}
@@ -0,0 +1,224 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bikeos/bosd/gps"
+)
+
+// gpsClientQueue bounds the messages queued for a client; clients that
+// fall further behind are dropped.
+const gpsClientQueue = 64
+
+// gpsdVersion greets gpsd clients.
+const gpsdVersion = `{"class":"VERSION","release":"bosd","rev":"bosd","proto_major":3,"proto_minor":11}` + "\r\n"
+
+// gpsServer rebroadcasts GPS messages to its clients.
+type gpsServer struct {
+	l       net.Listener
+	hello   []byte
+	mu      sync.Mutex
+	clients map[*gpsClient]struct{}
+	// wg tracks the clients' goroutines.
+	wg sync.WaitGroup
+}
+
+type gpsClient struct {
+	conn net.Conn
+	ch   chan []byte
+}
+
+// listen listens on a TCP host and port or a unix socket path.
+func listen(addr string) (net.Listener, error) {
+	if !strings.HasPrefix(addr, "/") {
+		return net.Listen("tcp", addr)
+	}
+	// Remove the socket left by an unclean exit.
+	if fi, err := os.Stat(addr); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		os.Remove(addr)
+	}
+	return net.Listen("unix", addr)
+}
+
+func (d *daemon) startGPSServers() (err error) {
+	if addr := d.cfg.NMEAServeAddr; addr != "" {
+		if d.nmeaSrv, err = d.startGPSServer(addr, nil); err != nil {
+			return err
+		}
+	}
+	if addr := d.cfg.GPSDServeAddr; addr != "" {
+		if d.gpsdSrv, err = d.startGPSServer(addr, []byte(gpsdVersion)); err != nil {
+			return err
+		}
+	}
+	if d.nmeaSrv != nil || d.gpsdSrv != nil {
+		d.worker("serve", restartBackoff, func(ctx context.Context) error {
+			return d.serveGPS(d.gps.Subscribe(ctx, gps.DropOldest))
+		})
+	}
+	return nil
+}
+
+// serveGPS publishes the GPS stream to the servers' clients.
+func (d *daemon) serveGPS(msgs <-chan gps.NMEA) error {
+	sv := gps.NewSkyView()
+	for msg := range msgs {
+		sv.Update(msg)
+		if l := msg.Line(); l != "" {
+			d.nmeaSrv.publish([]byte(l))
+		} else if msg.Valid() && !msg.Fix().IsZero() {
+			// UBX and gpsd fixes have no sentence to pass through.
+			fix := gps.NewFix(msg)
+			d.nmeaSrv.publish(append(gps.Encoder{}.RMC(fix), gps.Encoder{}.GGA(fix)...))
+		}
+		if msg.Valid() && !msg.Fix().IsZero() {
+			d.gpsdSrv.publishFix("", sv.Sky().Mode, msg)
+		}
+	}
+	return nil
+}
+
+// startGPSServer serves clients at an address, greeting each with hello.
+func (d *daemon) startGPSServer(addr string, hello []byte) (*gpsServer, error) {
+	l, err := listen(addr)
+	if err != nil {
+		return nil, err
+	}
+	srv := &gpsServer{
+		l:       l,
+		hello:   hello,
+		clients: make(map[*gpsClient]struct{}),
+	}
+	log.Infof("serving gps on %q", addr)
+	// Clients outlive a failed listener; only the daemon stopping
+	// drops them.
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		<-d.ctx.Done()
+		srv.mu.Lock()
+		for c := range srv.clients {
+			srv.drop(c)
+		}
+		srv.mu.Unlock()
+		srv.wg.Wait()
+	}()
+	d.worker("serve/"+addr, restartBackoff, func(ctx context.Context) error {
+		if srv.l == nil {
+			// Accept failed last time; listen again.
+			l, err := listen(addr)
+			if err != nil {
+				return err
+			}
+			srv.l = l
+		}
+		l := srv.l
+		donec := make(chan struct{})
+		defer close(donec)
+		go func() {
+			select {
+			case <-ctx.Done():
+				l.Close()
+			case <-donec:
+			}
+		}()
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				l.Close()
+				srv.l = nil
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+			srv.add(conn)
+		}
+	})
+	return srv, nil
+}
+
+func (srv *gpsServer) add(conn net.Conn) {
+	c := &gpsClient{conn: conn, ch: make(chan []byte, gpsClientQueue)}
+	if srv.hello != nil {
+		c.ch <- srv.hello
+	}
+	srv.mu.Lock()
+	srv.clients[c] = struct{}{}
+	srv.mu.Unlock()
+	srv.wg.Add(2)
+	go func() {
+		defer srv.wg.Done()
+		for b := range c.ch {
+			if _, err := conn.Write(b); err != nil {
+				break
+			}
+		}
+		srv.remove(c)
+	}()
+	go func() {
+		defer srv.wg.Done()
+		// Ignore requests; the stream starts on connect.
+		io.Copy(ioutil.Discard, conn)
+		srv.remove(c)
+	}()
+}
+
+func (srv *gpsServer) remove(c *gpsClient) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if _, ok := srv.clients[c]; ok {
+		srv.drop(c)
+	}
+}
+
+// drop disconnects a client. Must hold the lock.
+func (srv *gpsServer) drop(c *gpsClient) {
+	delete(srv.clients, c)
+	close(c.ch)
+	c.conn.Close()
+}
+
+// publish queues a message for every client, dropping clients that
+// have fallen behind instead of waiting on them.
+func (srv *gpsServer) publish(b []byte) {
+	if srv == nil {
+		return
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	for c := range srv.clients {
+		select {
+		case c.ch <- b:
+		default:
+			log.Infof("gps: dropping slow client %v", c.conn.RemoteAddr())
+			srv.drop(c)
+		}
+	}
+}
+
+// publishFix sends a fix as a gpsd TPV report.
+func (srv *gpsServer) publishFix(device string, mode gps.FixMode, fix gps.NMEAi) {
+	if srv == nil {
+		return
+	}
+	if mode < gps.Fix2D {
+		// Fixes are at least 2D even if no GSA was seen.
+		mode = gps.Fix2D
+	}
+	b, err := json.Marshal(gps.NewTPV(device, mode, fix))
+	if err != nil {
+		log.Errorf("gps: encoding TPV: %v", err)
+		return
+	}
+	srv.publish(append(b, '\r', '\n'))
+}
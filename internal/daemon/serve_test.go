@@ -0,0 +1,175 @@
+package daemon
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"math"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bikeos/bosd/gps"
+)
+
+type testFix struct{}
+
+func (testFix) Fix() time.Time     { return time.Date(2018, 3, 12, 2, 55, 3, 0, time.UTC) }
+func (testFix) Longitude() float64 { return -111.25 }
+func (testFix) Latitude() float64  { return 48.5 }
+func (testFix) Speed() float64     { return 5.5 }
+func (testFix) Course() float64    { return math.NaN() }
+func (testFix) MagVar() float64    { return math.NaN() }
+func (testFix) Valid() bool        { return true }
+
+func TestGPSServer(t *testing.T) {
+	d := &daemon{ctx: newDaemonCtx()}
+	defer func() {
+		d.ctx.Cancel(nil)
+		d.wg.Wait()
+	}()
+	sock := filepath.Join(t.TempDir(), "gpsd.sock")
+	srv, err := d.startGPSServer(sock, []byte(gpsdVersion))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dial := func() (net.Conn, *bufio.Reader) {
+		conn, err := net.Dial("unix", sock)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := bufio.NewReader(conn)
+		if l, err := r.ReadString('\n'); err != nil || l != gpsdVersion {
+			t.Fatalf("expected version, got %q (%v)", l, err)
+		}
+		return conn, r
+	}
+	fast, fr := dial()
+	defer fast.Close()
+	slow, _ := dial()
+	defer slow.Close()
+
+	srv.publishFix("", 0, testFix{})
+	l, err := fr.ReadBytes('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tpv struct {
+		Class string
+		Mode  int
+		Time  string
+		Lat   float64
+		Lon   float64
+		Track *float64
+	}
+	if err := json.Unmarshal(l, &tpv); err != nil {
+		t.Fatal(err)
+	}
+	if tpv.Class != "TPV" || tpv.Mode != int(gps.Fix2D) || tpv.Lat != 48.5 || tpv.Lon != -111.25 ||
+		tpv.Time != "2018-03-12T02:55:03.000Z" || tpv.Track != nil {
+		t.Errorf("bad TPV %s", l)
+	}
+
+	// The slow client never reads; it is dropped once its queue fills.
+	readc, donec := make(chan error), make(chan struct{})
+	defer close(donec)
+	go func() {
+		for {
+			_, err := fr.ReadBytes('\n')
+			select {
+			case readc <- err:
+			case <-donec:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	line := append(bytes.Repeat([]byte("x"), 4095), '\n')
+	for i := 0; ; i++ {
+		srv.publish(line)
+		if err := <-readc; err != nil {
+			t.Fatalf("fast client dropped: %v", err)
+		}
+		srv.mu.Lock()
+		n := len(srv.clients)
+		srv.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		if i > 10000 {
+			t.Fatalf("slow client not dropped")
+		}
+	}
+	if _, err := slow.Read(make([]byte, 1)); err != nil {
+		t.Fatalf("expected queued data for slow client, got %v", err)
+	}
+}
+
+// TestGPSServerRelisten checks that a server listens again after Accept
+// fails, keeping its clients.
+func TestGPSServerRelisten(t *testing.T) {
+	defer func(min time.Duration) { minBackoff = min }(minBackoff)
+	minBackoff = time.Millisecond
+	d := &daemon{ctx: newDaemonCtx()}
+	st := Settings{}.withDefaults()
+	st.Flite = "/nonexistent/flite"
+	d.setSettings(st)
+	defer func() {
+		d.ctx.Cancel(nil)
+		d.wg.Wait()
+	}()
+	sock := filepath.Join(t.TempDir(), "nmea.sock")
+	srv, err := d.startGPSServer(sock, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitClients := func(want int) {
+		for i := 0; ; i++ {
+			srv.mu.Lock()
+			n := len(srv.clients)
+			srv.mu.Unlock()
+			if n == want {
+				return
+			}
+			if i > 1000 {
+				t.Fatalf("wanted %d clients, got %d", want, n)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	old, err := net.Dial("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer old.Close()
+	waitClients(1)
+
+	// Fail the next Accept.
+	srv.l.Close()
+	var conn net.Conn
+	for i := 0; ; i++ {
+		if conn, err = net.Dial("unix", sock); err == nil {
+			break
+		}
+		if i > 1000 {
+			t.Fatalf("not listening again: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	defer conn.Close()
+	waitClients(2)
+	if d.ctx.ctx.Err() != nil {
+		t.Fatal("daemon stopped")
+	}
+	srv.publish([]byte("$GPTXT\r\n"))
+	for _, c := range []net.Conn{old, conn} {
+		c.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if l, err := bufio.NewReader(c).ReadString('\n'); err != nil || l != "$GPTXT\r\n" {
+			t.Errorf("expected sentence, got %q (%v)", l, err)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bikeos/bosd/audio"
+)
+
+// SettingsFile is the daemon's config file in the data directory.
+const SettingsFile = "config/bosd.json"
+
+// Settings tune the daemon from the config file. Zero values take
+// defaults.
+type Settings struct {
+	// ReportInterval is the time between spoken reports when there is
+	// no gamepad to ask for them; defaults to 20s.
+	ReportInterval Duration `json:"report_interval"`
+	// WifiHop is the time on each channel while capturing, and between
+	// looking for wifi devices while there are none; defaults to 3s.
+	WifiHop Duration `json:"wifi_hop"`
+	// WifiScan is the time between looking for new wifi devices while
+	// capturing; defaults to 30s.
+	WifiScan Duration `json:"wifi_scan"`
+	// WifiStagger is the wait between starting wifi devices, as starting
+	// them all at once draws a lot of power; defaults to 500ms.
+	WifiStagger Duration `json:"wifi_stagger"`
+	// WifiStall is how long a capturing device may go without receiving
+	// a frame before each step of its recovery; defaults to 1m.
+	WifiStall Duration `json:"wifi_stall"`
+	// ShutdownTimeout bounds stopping captures, closing logs, and
+	// playing the shutdown sound; defaults to 10s.
+	ShutdownTimeout Duration `json:"shutdown_timeout"`
+	// PCapSize is the size in megabytes at which tcpdump starts a new
+	// capture file; defaults to 4.
+	PCapSize int `json:"pcap_size"`
+	// Gamepad is the name of the input device for asking for reports;
+	// defaults to "usb gamepad".
+	Gamepad string `json:"gamepad"`
+	// MPG321 and Flite are the paths of the programs for sounds and
+	// speech.
+	MPG321 string `json:"mpg321"`
+	Flite  string `json:"flite"`
+	// GPSDevices and WifiDevices are the names of the devices to record
+	// (e.g., "ttyACM0" and "wlan1"); empty records every device.
+	GPSDevices  []string `json:"gps_devices,omitempty"`
+	WifiDevices []string `json:"wifi_devices,omitempty"`
+}
+
+// allowed is set if a device is on an allow-list.
+func allowed(list []string, name string) bool {
+	return len(list) == 0 || contains(list, name)
+}
+
+func contains(list []string, name string) bool {
+	for _, n := range list {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Duration is a time.Duration written as a string, such as "20s".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("bad duration %s; want a string such as \"20s\"", b)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// ReadSettings reads the config file from a data directory, taking
+// defaults if there is none.
+func ReadSettings(dataDir string) (st Settings, err error) {
+	p := filepath.Join(dataDir, SettingsFile)
+	b, err := ioutil.ReadFile(p)
+	if err != nil && !os.IsNotExist(err) {
+		return st, err
+	}
+	if err == nil {
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&st); err != nil {
+			return st, fmt.Errorf("daemon: %s: %v", p, err)
+		}
+	}
+	st = st.withDefaults()
+	if err := st.validate(); err != nil {
+		return st, fmt.Errorf("daemon: %s: %v", p, err)
+	}
+	return st, nil
+}
+
+// withDefaults fills in unset settings.
+func (st Settings) withDefaults() Settings {
+	durs := []struct {
+		d   *Duration
+		def time.Duration
+	}{
+		{&st.ReportInterval, 20 * time.Second},
+		{&st.WifiHop, 3 * time.Second},
+		{&st.WifiScan, 30 * time.Second},
+		{&st.WifiStagger, 500 * time.Millisecond},
+		{&st.WifiStall, time.Minute},
+		{&st.ShutdownTimeout, 10 * time.Second},
+	}
+	for _, d := range durs {
+		if *d.d == 0 {
+			*d.d = Duration(d.def)
+		}
+	}
+	if st.PCapSize == 0 {
+		st.PCapSize = 4
+	}
+	if st.Gamepad == "" {
+		st.Gamepad = "usb gamepad"
+	}
+	if st.MPG321 == "" {
+		st.MPG321 = audio.DefaultMPG321
+	}
+	if st.Flite == "" {
+		st.Flite = audio.DefaultFlite
+	}
+	return st
+}
+
+// validate checks settings with defaults filled in.
+func (st Settings) validate() error {
+	durs := []struct {
+		name string
+		d    Duration
+	}{
+		{"report_interval", st.ReportInterval},
+		{"wifi_hop", st.WifiHop},
+		{"wifi_scan", st.WifiScan},
+		{"wifi_stagger", st.WifiStagger},
+		{"wifi_stall", st.WifiStall},
+		{"shutdown_timeout", st.ShutdownTimeout},
+	}
+	for _, d := range durs {
+		if d.d < 0 {
+			return fmt.Errorf("%s: negative duration %v", d.name, time.Duration(d.d))
+		}
+	}
+	if st.PCapSize < 0 {
+		return fmt.Errorf("pcap_size: negative size %d", st.PCapSize)
+	}
+	for _, p := range []struct{ name, path string }{{"mpg321", st.MPG321}, {"flite", st.Flite}} {
+		if !filepath.IsAbs(p.path) {
+			return fmt.Errorf("%s: path %q is not absolute", p.name, p.path)
+		}
+	}
+	return nil
+}
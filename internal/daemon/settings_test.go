@@ -0,0 +1,85 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadSettings(t *testing.T) {
+	var tts = []struct {
+		// file is the config file; empty if there is none.
+		file string
+
+		want Settings
+		err  string
+	}{
+		{
+			"",
+			Settings{}.withDefaults(),
+			"",
+		},
+		{
+			`{"report_interval": "1m", "pcap_size": 16, "gamepad": "pad", "wifi_devices": ["wlan1"]}`,
+			func() Settings {
+				st := Settings{}.withDefaults()
+				st.ReportInterval = Duration(time.Minute)
+				st.PCapSize = 16
+				st.Gamepad = "pad"
+				st.WifiDevices = []string{"wlan1"}
+				return st
+			}(),
+			"",
+		},
+		{
+			`{"wifi_hop": 3}`,
+			Settings{},
+			"bad duration 3",
+		},
+		{
+			`{"wifi_hop": "-1s"}`,
+			Settings{},
+			"wifi_hop: negative duration",
+		},
+		{
+			`{"flite": "flite"}`,
+			Settings{},
+			"not absolute",
+		},
+		{
+			`{"pcapsize": 16}`,
+			Settings{},
+			"unknown field",
+		},
+	}
+	for i, tt := range tts {
+		dir := t.TempDir()
+		if tt.file != "" {
+			p := filepath.Join(dir, SettingsFile)
+			if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(p, []byte(tt.file), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		st, err := ReadSettings(dir)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%d: got error %v, want %q", i, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(st, tt.want) {
+			t.Errorf("%d: got %+v, want %+v", i, st, tt.want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package daemon
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bikeos/bosd/systemd"
+)
+
+// watchSignals reloads the config file on SIGHUP and stops the daemon
+// on SIGTERM or SIGINT. A second SIGTERM or SIGINT kills it.
+func (d *daemon) watchSignals(ctx context.Context) error {
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGHUP, syscall.SIGTERM, syscall.SIGINT)
+	defer signal.Stop(sigc)
+	for {
+		select {
+		case sig := <-sigc:
+			if sig == syscall.SIGHUP {
+				d.reload()
+				continue
+			}
+			log.Infof("%v: shutting down", sig)
+			d.ctx.Cancel(nil)
+			return nil
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// shutdown stops the workers, closes the trip's logs, and plays the
+// shutdown sound, giving up on what is left after the timeout.
+func (d *daemon) shutdown() {
+	st, _ := d.settings()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(st.ShutdownTimeout))
+	defer cancel()
+	if _, err := systemd.Notify("STOPPING=1"); err != nil {
+		log.Errorf("shutdown: %v", err)
+	}
+	d.ctx.Cancel(io.EOF)
+	donec := make(chan struct{})
+	go func() {
+		// Captures stop with the daemon's context, gzipping their last
+		// file and restoring their interfaces.
+		d.wg.Wait()
+		close(donec)
+	}()
+	select {
+	case <-donec:
+	case <-ctx.Done():
+		log.Warnf("shutdown: workers still running after %v", time.Duration(st.ShutdownTimeout))
+	}
+	if d.sel != nil {
+		d.sel.Close()
+	}
+	if d.s == nil {
+		return
+	}
+	d.saveWorkers()
+	if err := d.s.Close(); err != nil {
+		log.Errorf("shutdown: %v", err)
+	}
+	if err := d.player().Play(ctx, d.s.ConfigPath("shutdown.mp3")); err != nil {
+		log.Errorf("shutdown: audio: %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package daemon
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestShutdown(t *testing.T) {
+	dir := t.TempDir()
+	d := &daemon{cfg: Config{OutDirPath: dir}, ctx: newDaemonCtx()}
+	st := Settings{}.withDefaults()
+	st.ShutdownTimeout = Duration(100 * time.Millisecond)
+	st.MPG321 = "/nonexistent/mpg321"
+	d.setSettings(st)
+	var err error
+	if d.s, err = newStore(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.s.GPS().write(&d.s.GPS().nmea, "nmea.log", []byte("$GPRMC\r\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	// One worker stops with the daemon and one never does.
+	stuckc := make(chan struct{})
+	defer close(stuckc)
+	stoppedc := make(chan struct{})
+	d.worker("stops", restartFatal, func(ctx context.Context) error {
+		<-ctx.Done()
+		close(stoppedc)
+		return nil
+	})
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		<-stuckc
+	}()
+
+	start := time.Now()
+	d.shutdown()
+	if took := time.Since(start); took > 5*time.Second {
+		t.Errorf("shutdown took %v", took)
+	}
+	select {
+	case <-stoppedc:
+	default:
+		t.Error("worker not stopped")
+	}
+	b, err := ioutil.ReadFile(filepath.Join(d.s.nowdir, "gps", "nmea.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "$GPRMC\r\n" {
+		t.Errorf("got log %q", b)
+	}
+	if _, err := d.s.GPS().nmea.Write([]byte("x")); err == nil {
+		t.Error("log not closed")
+	}
+}
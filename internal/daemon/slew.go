@@ -0,0 +1,18 @@
+package daemon
+
+import (
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// adjOffsetSingleshot is ADJ_OFFSET_SINGLESHOT, the adjtime(3) mode of
+// adjtimex(2).
+const adjOffsetSingleshot = 0x8001
+
+// slewClock gradually moves the system clock by an offset.
+func slewClock(off time.Duration) error {
+	tx := unix.Timex{Modes: adjOffsetSingleshot, Offset: timexOffset(off)}
+	_, err := unix.Adjtimex(&tx)
+	return err
+}
@@ -0,0 +1,8 @@
+// +build !386,!arm,!mips,!mipsle
+
+package daemon
+
+import "time"
+
+// timexOffset is an offset in the microseconds of a 64-bit Timex.
+func timexOffset(off time.Duration) int64 { return int64(off / time.Microsecond) }
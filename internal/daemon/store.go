@@ -1,17 +1,24 @@
 package daemon
 
 import (
+	"encoding/json"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/bikeos/bosd/gps"
 )
 
 type store struct {
 	basedir string
 	nowdir  string
-	gps     *os.File
+	gps     *gpsLogs
+	evMu    sync.Mutex
 }
 
 func newStore(basedir string) (*store, error) {
@@ -25,46 +32,159 @@ func newStore(basedir string) (*store, error) {
 	s := &store{
 		basedir: basedir,
 		nowdir:  nd,
+		gps:     &gpsLogs{dir: filepath.Join(nd, "gps")},
 	}
 	return s, nil
 }
 
-func (s *store) Close() (err error) {
-	if s.gps != nil {
-		err = s.gps.Close()
+func (s *store) Close() error { return s.gps.Close() }
+
+// GPS is the log of the selected GPS stream.
+func (s *store) GPS() *gpsLogs { return s.gps }
+
+// Receiver is a new log of a GPS receiver's own stream.
+func (s *store) Receiver(name string) *gpsLogs {
+	return &gpsLogs{dir: filepath.Join(s.nowdir, "gps", name)}
+}
+
+// gpsLogs are the log files of a GPS stream, opened on first use.
+type gpsLogs struct {
+	dir  string
+	nmea *os.File
+	ubx  *os.File
+	gpsd *os.File
+}
+
+// Write logs a message: UBX frames to ubx.log, gpsd reports to gpsd.log,
+// and NMEA sentences to nmea.log.
+func (l *gpsLogs) Write(msg gps.NMEA) error {
+	if frame := msg.UBX(); frame != nil {
+		return l.write(&l.ubx, "ubx.log", frame)
+	}
+	if report := msg.JSON(); report != nil {
+		return l.write(&l.gpsd, "gpsd.log", report)
 	}
+	return l.write(&l.nmea, "nmea.log", []byte(msg.Line()))
+}
+
+func (l *gpsLogs) write(fp **os.File, name string, b []byte) error {
+	if *fp == nil {
+		if err := os.MkdirAll(l.dir, 0755); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(filepath.Join(l.dir, name), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		*fp = f
+	}
+	_, err := (*fp).Write(b)
 	return err
 }
 
-// GPS is the stream for NMEA message output.
-func (s *store) GPS() (io.Writer, error) {
-	if s.gps != nil {
-		return s.gps, nil
+// Close syncs and closes the log files.
+func (l *gpsLogs) Close() (err error) {
+	for _, f := range []*os.File{l.nmea, l.ubx, l.gpsd} {
+		if f == nil {
+			continue
+		}
+		if serr := f.Sync(); err == nil {
+			err = serr
+		}
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
 	}
-	gpsd := filepath.Join(s.nowdir, "gps")
-	if err := os.MkdirAll(gpsd, 0755); err != nil {
-		return nil, err
+	return err
+}
+
+// SetClock records a change of the system clock during the trip.
+func (s *store) SetClock(rec clockRecord) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
 	}
-	gpslog := filepath.Join(gpsd, "nmea.log")
-	f, err := os.OpenFile(gpslog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	return ioutil.WriteFile(filepath.Join(s.nowdir, "clock.json"), append(b, '\n'), 0644)
+}
+
+// tripEvent is an entry in the trip's event log.
+type tripEvent struct {
+	At     time.Time `json:"at"`
+	Source string    `json:"source"`
+	Event  string    `json:"event"`
+	Detail string    `json:"detail,omitempty"`
+}
+
+// Event appends an action taken by the daemon to the trip's events.log.
+func (s *store) Event(source, event, detail string) error {
+	b, err := json.Marshal(tripEvent{time.Now(), source, event, detail})
 	if err != nil {
-		return nil, err
+		return err
 	}
-	s.gps = f
-	return s.gps, nil
+	s.evMu.Lock()
+	defer s.evMu.Unlock()
+	f, err := os.OpenFile(filepath.Join(s.nowdir, "events.log"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
 }
 
-// WIFI is the directory for a wifi device.
+// SetWorkers records the supervision of the workers during the trip.
+func (s *store) SetWorkers(wss []workerStatus) error {
+	b, err := json.MarshalIndent(wss, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(s.nowdir, "workers.json"), append(b, '\n'), 0644)
+}
+
+// Wifi is a new directory for a wifi device's capture. A restarted
+// capture gets its own directory (e.g., wlan1.1) so tcpdump cannot
+// overwrite the files of the last one; readers merge the directories
+// back into the device's.
 func (s *store) Wifi(name string) (string, error) {
+	for i := 0; ; i++ {
+		wifid := s.wifiDir(name, i)
+		f, err := os.Open(wifid)
+		if os.IsNotExist(err) {
+			return wifid, os.MkdirAll(wifid, 0755)
+		}
+		if err != nil {
+			return "", err
+		}
+		names, err := f.Readdirnames(1)
+		f.Close()
+		if len(names) == 0 && err == io.EOF {
+			return wifid, nil
+		}
+	}
+}
+
+// wifiDir is the directory of a device's i-th capture.
+func (s *store) wifiDir(name string, i int) string {
 	wifid := filepath.Join(s.nowdir, "wifi", name)
-	if err := os.MkdirAll(wifid, 0755); err != nil {
-		return "", err
+	if i > 0 {
+		wifid += "." + strconv.Itoa(i)
 	}
-	return wifid, nil
+	return wifid
 }
 
+// WifiCurrentPCap is the file the device's latest capture is writing.
 func (s *store) WifiCurrentPCap(name string) (string, error) {
-	f, err := os.Open(filepath.Join(s.nowdir, "wifi", name))
+	wifid := s.wifiDir(name, 0)
+	for i := 1; ; i++ {
+		next := s.wifiDir(name, i)
+		if _, err := os.Stat(next); err != nil {
+			break
+		}
+		wifid = next
+	}
+	f, err := os.Open(wifid)
 	if err != nil {
 		return "", err
 	}
@@ -75,14 +195,14 @@ func (s *store) WifiCurrentPCap(name string) (string, error) {
 	}
 	for _, pcap := range pcaps {
 		if !strings.HasSuffix(pcap, ".gz") {
-			return filepath.Join(s.nowdir, "wifi", name, pcap), nil
+			return filepath.Join(wifid, pcap), nil
 		}
 	}
 	return "", io.EOF
 }
 
 func syspath(p string) string            { return filepath.Join("/usr/share/bikeos", p) }
-func (s *store) cfgpath(p string) string { return filepath.Join(s.basedir, "config", "boot.mp3") }
+func (s *store) cfgpath(p string) string { return filepath.Join(s.basedir, "config", p) }
 
 func (s *store) ConfigPath(p string) string {
 	cpath := s.cfgpath(p)
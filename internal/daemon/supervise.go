@@ -0,0 +1,179 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// restartPolicy decides what happens when a worker returns before the
+// daemon stops.
+type restartPolicy int
+
+const (
+	// restartFatal stops the daemon if the worker fails.
+	restartFatal restartPolicy = iota
+	// restartBackoff restarts a failed worker, doubling the wait with
+	// each failure in a row. A worker returning nil is done.
+	restartBackoff
+	// restartAlways restarts the worker whenever it returns.
+	restartAlways
+)
+
+func (p restartPolicy) String() string {
+	switch p {
+	case restartFatal:
+		return "fatal"
+	case restartBackoff:
+		return "backoff"
+	case restartAlways:
+		return "always"
+	}
+	return fmt.Sprintf("restartPolicy(%d)", int(p))
+}
+
+// minBackoff and maxBackoff bound the wait before a restart. A worker
+// that ran for maxBackoff before failing waits minBackoff again.
+var (
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+)
+
+// workerStatus is the supervision record of a worker.
+type workerStatus struct {
+	Name    string `json:"name"`
+	Policy  string `json:"policy"`
+	Running bool   `json:"running"`
+	// External workers read a device or an outside service; their
+	// failures do not make the daemon unhealthy.
+	External bool `json:"external,omitempty"`
+	// Restarts counts the times the worker was started again.
+	Restarts int `json:"restarts"`
+	// Failures counts the failures in a row.
+	Failures  int       `json:"failures"`
+	LastError string    `json:"last_error,omitempty"`
+	LastFail  time.Time `json:"last_fail,omitempty"`
+}
+
+// worker runs f under supervision until it is done or the daemon stops.
+// Names a child with its parent's name and a slash (e.g., "gps/ttyACM0").
+func (d *daemon) worker(name string, p restartPolicy, f func(ctx context.Context) error) {
+	d.startWorker(name, p, false, f)
+}
+
+// externalWorker is a worker for a device or an outside service.
+func (d *daemon) externalWorker(name string, p restartPolicy, f func(ctx context.Context) error) {
+	d.startWorker(name, p, true, f)
+}
+
+func (d *daemon) startWorker(name string, p restartPolicy, external bool, f func(ctx context.Context) error) {
+	d.wMu.Lock()
+	if d.workers == nil {
+		d.workers = make(map[string]*workerStatus)
+	}
+	if _, ok := d.workers[name]; !ok {
+		d.workers[name] = &workerStatus{Name: name}
+	}
+	d.workers[name].Policy = p.String()
+	d.workers[name].External = external
+	d.wMu.Unlock()
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.supervise(name, p, f)
+	}()
+}
+
+func (d *daemon) supervise(name string, p restartPolicy, f func(ctx context.Context) error) {
+	ctx := d.ctx.ctx
+	backoff := minBackoff
+	for {
+		d.updateWorker(name, func(ws *workerStatus) { ws.Running = true })
+		start := time.Now()
+		err := f(ctx)
+		d.updateWorker(name, func(ws *workerStatus) {
+			ws.Running = false
+			if err == nil || time.Since(start) >= maxBackoff {
+				ws.Failures = 0
+			}
+			if err != nil {
+				ws.Failures++
+			}
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Errorf("%s: %v", name, err)
+			d.updateWorker(name, func(ws *workerStatus) {
+				ws.LastError, ws.LastFail = err.Error(), time.Now()
+			})
+			d.saveWorkers()
+		}
+		wait := minBackoff
+		switch {
+		case err != nil && p == restartFatal:
+			d.player().Say(ctx, fmt.Sprintf("%v", err))
+			d.ctx.Cancel(err)
+			return
+		case err == nil && p != restartAlways:
+			return
+		case err != nil && p == restartBackoff:
+			if time.Since(start) >= maxBackoff {
+				backoff = minBackoff
+			}
+			wait, backoff = backoff, backoff*2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return
+		}
+		d.updateWorker(name, func(ws *workerStatus) { ws.Restarts++ })
+		log.Infof("%s: restarting", name)
+	}
+}
+
+func (d *daemon) updateWorker(name string, f func(*workerStatus)) {
+	d.wMu.Lock()
+	defer d.wMu.Unlock()
+	f(d.workers[name])
+}
+
+// workerStatuses are the records of every worker, sorted by name.
+func (d *daemon) workerStatuses() []workerStatus {
+	d.wMu.Lock()
+	defer d.wMu.Unlock()
+	wss := make([]workerStatus, 0, len(d.workers))
+	for _, ws := range d.workers {
+		wss = append(wss, *ws)
+	}
+	sort.Slice(wss, func(i, j int) bool { return wss[i].Name < wss[j].Name })
+	return wss
+}
+
+// event records an action in the trip's event log.
+func (d *daemon) event(source, event, detail string) {
+	if d.s == nil {
+		return
+	}
+	if err := d.s.Event(source, event, detail); err != nil {
+		log.Errorf("event: %v", err)
+	}
+}
+
+// saveWorkers records the workers in the trip.
+func (d *daemon) saveWorkers() {
+	if d.s == nil {
+		return
+	}
+	if err := d.s.SetWorkers(d.workerStatuses()); err != nil {
+		log.Errorf("supervise: %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSupervise(t *testing.T) {
+	defer func(min, max time.Duration) { minBackoff, maxBackoff = min, max }(minBackoff, maxBackoff)
+	minBackoff, maxBackoff = time.Millisecond, 4*time.Millisecond
+	errFail := errors.New("fail")
+	var tts = []struct {
+		p restartPolicy
+		// results are returned by each run; the last run waits for the
+		// daemon to stop.
+		results []error
+
+		restarts int
+		lastErr  string
+		fatal    bool
+	}{
+		{restartBackoff, []error{errFail, errFail, nil}, 2, "fail", false},
+		{restartAlways, []error{nil, errFail, nil}, 3, "fail", false},
+		{restartFatal, []error{errFail}, 0, "fail", true},
+		{restartFatal, []error{nil}, 0, "", false},
+	}
+	for i, tt := range tts {
+		d := &daemon{ctx: newDaemonCtx()}
+		st := Settings{}.withDefaults()
+		st.Flite = "/nonexistent/flite"
+		d.setSettings(st)
+		runs := 0
+		donec := make(chan struct{})
+		d.worker("w", tt.p, func(ctx context.Context) error {
+			runs++
+			if runs <= len(tt.results) {
+				return tt.results[runs-1]
+			}
+			close(donec)
+			<-ctx.Done()
+			return nil
+		})
+		// Wait for the worker to finish its runs before stopping it.
+		select {
+		case <-donec:
+		case <-d.ctx.Done():
+		case <-time.After(100 * time.Millisecond):
+		}
+		var err error
+		select {
+		case err = <-d.ctx.errc:
+		default:
+		}
+		if (err != nil) != tt.fatal {
+			t.Errorf("%d: got daemon error %v, want fatal %v", i, err, tt.fatal)
+		}
+		d.ctx.Cancel(nil)
+		d.wg.Wait()
+		wss := d.workerStatuses()
+		if len(wss) != 1 {
+			t.Fatalf("%d: got %d workers", i, len(wss))
+		}
+		ws := wss[0]
+		if ws.Name != "w" || ws.Policy != tt.p.String() || ws.Running {
+			t.Errorf("%d: got %+v", i, ws)
+		}
+		if ws.Restarts != tt.restarts || ws.LastError != tt.lastErr {
+			t.Errorf("%d: got %d restarts, last error %q; want %d, %q",
+				i, ws.Restarts, ws.LastError, tt.restarts, tt.lastErr)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package daemon
+
+import (
+	"time"
+)
+
+// stallAction is a step in recovering a stalled capture.
+type stallAction int
+
+const (
+	stallNone stallAction = iota
+	// stallRetune tunes the radio again.
+	stallRetune
+	// stallReset takes the device down and back up in monitor mode.
+	stallReset
+	// stallRestart restarts the capture.
+	stallRestart
+	// stallDead gives up on the device.
+	stallDead
+)
+
+func (a stallAction) String() string {
+	return [...]string{"none", "retune", "reset", "restart", "dead"}[a]
+}
+
+// captureWatchdog escalates the recovery of a capture whose device stops
+// receiving frames. It outlives capture restarts.
+type captureWatchdog struct {
+	last   uint64
+	lastAt time.Time
+	action stallAction
+	// capturing is set while tcpdump runs.
+	capturing bool
+}
+
+// check takes the device's frame count, returning the next recovery
+// step once the count has not moved for the stall time. Each step gets
+// a full stall time to work before the next.
+func (wd *captureWatchdog) check(n uint64, now time.Time, stall time.Duration) stallAction {
+	if n != wd.last || wd.lastAt.IsZero() {
+		wd.last, wd.lastAt, wd.action = n, now, stallNone
+		return stallNone
+	}
+	if wd.action == stallDead || now.Sub(wd.lastAt) < stall {
+		return stallNone
+	}
+	wd.lastAt = now
+	wd.action++
+	return wd.action
+}
+
+// dead is set once the watchdog gave up on the device.
+func (wd *captureWatchdog) dead() bool { return wd.action == stallDead }
@@ -0,0 +1,87 @@
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCaptureWatchdog(t *testing.T) {
+	const stall = time.Minute
+	start := time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC)
+	var tts = []struct {
+		after time.Duration
+		n     uint64
+
+		want stallAction
+	}{
+		{0, 10, stallNone},
+		{30 * time.Second, 20, stallNone},
+		// Each step waits a full stall time after the last.
+		{89 * time.Second, 20, stallNone},
+		{90 * time.Second, 20, stallRetune},
+		{2 * time.Minute, 20, stallNone},
+		{150 * time.Second, 20, stallReset},
+		// Frames again; start over.
+		{160 * time.Second, 21, stallNone},
+		{220 * time.Second, 21, stallRetune},
+		{280 * time.Second, 21, stallReset},
+		{340 * time.Second, 21, stallRestart},
+		{400 * time.Second, 21, stallDead},
+		{time.Hour, 21, stallNone},
+	}
+	var wd captureWatchdog
+	for i, tt := range tts {
+		if got := wd.check(tt.n, start.Add(tt.after), stall); got != tt.want {
+			t.Errorf("%d: got %v, want %v", i, got, tt.want)
+		}
+	}
+	if !wd.dead() {
+		t.Error("not dead")
+	}
+}
+
+func TestEvent(t *testing.T) {
+	s, err := newStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []tripEvent{
+		{Source: "wifi/wlan1", Event: "retune", Detail: "rx_packets 5"},
+		{Source: "wifi/wlan1", Event: "dead"},
+	}
+	for _, ev := range want {
+		if err := s.Event(ev.Source, ev.Event, ev.Detail); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f, err := os.Open(filepath.Join(s.nowdir, "events.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var got []tripEvent
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var ev tripEvent
+		if err := json.Unmarshal(sc.Bytes(), &ev); err != nil {
+			t.Fatal(err)
+		}
+		if ev.At.IsZero() {
+			t.Errorf("%+v: no time", ev)
+		}
+		ev.At = time.Time{}
+		got = append(got, ev)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d events, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("%d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -2,6 +2,10 @@ package daemon
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -10,26 +14,32 @@ import (
 )
 
 type wifiMon struct {
-	devs map[string]*wlan.Wifi
-}
+	d *daemon
+	// devs stops the capture of each device.
+	devs map[string]context.CancelFunc
 
-var switchTime = 3 * time.Second
-var watchDogTime = 30 * time.Second
-var bootStaggerTime = 500 * time.Millisecond
+	// dogs watch the captures for stalls.
+	dogs   map[string]*captureWatchdog
+	dogsMu sync.Mutex
+}
 
 func (d *daemon) startWifi() error {
-	wm := &wifiMon{devs: make(map[string]*wlan.Wifi)}
+	wm := &wifiMon{
+		d:    d,
+		devs: make(map[string]context.CancelFunc),
+		dogs: make(map[string]*captureWatchdog),
+	}
+	d.wm = wm
 	if _, werr := wlan.Enumerate(); werr != nil {
 		return werr
 	}
-	d.worker(func(ctx context.Context) error {
-		return wm.monDevs(ctx, d.s)
-	})
+	d.worker("wifi", restartBackoff, wm.monDevs)
 	return nil
 }
 
-func (wm *wifiMon) monDevs(ctx context.Context, s *store) error {
+func (wm *wifiMon) monDevs(ctx context.Context) error {
 	for {
+		st, changed := wm.d.settings()
 		wdevs, werr := wlan.Enumerate()
 		if werr != nil {
 			return werr
@@ -38,40 +48,53 @@ func (wm *wifiMon) monDevs(ctx context.Context, s *store) error {
 		for _, wdev := range wdevs {
 			curdevs[wdev.Name()] = wdev
 		}
-		for n := range wm.devs {
+		for n, stop := range wm.devs {
 			if _, ok := curdevs[n]; !ok {
 				log.Infof("wifi %q removed", n)
 			}
+			if !allowed(st.WifiDevices, n) {
+				log.Infof("wifi %q not allowed; stopping", n)
+				stop()
+				delete(wm.devs, n)
+			}
 		}
-		// TODO: check logger if it's bricked
 		for n, dev := range curdevs {
-			if _, ok := wm.devs[n]; ok {
+			if _, ok := wm.devs[n]; ok || !allowed(st.WifiDevices, n) {
 				continue
 			}
 			w, err := wlan.NewWifi(dev)
 			if err != nil {
 				log.Error(err)
+				continue
 			}
-			wm.devs[n] = w
+			wctx, cancel := context.WithCancel(ctx)
+			wm.devs[n] = cancel
+			wd := &captureWatchdog{}
+			wm.dogsMu.Lock()
+			wm.dogs[n] = wd
+			wm.dogsMu.Unlock()
 
 			// Booting all devices at once seems to drain
 			// a lot of power; play it safe and stagger.
-			time.Sleep(bootStaggerTime)
+			if !wm.d.wait(ctx, func(st Settings) Duration { return st.WifiStagger }) {
+				return nil
+			}
 
-			go func() {
-				// Treat logger errors as soft errors.
-				if err := wifiLogger(w, s); err != nil {
-					log.Errorf("wifi: %v", err)
+			wm.d.externalWorker("wifi/"+n, restartBackoff, func(context.Context) error {
+				if wctx.Err() != nil {
+					return nil
 				}
-			}()
+				return wm.d.wifiLogger(wctx, w, wd, &wm.dogsMu)
+			})
 		}
 
-		updateTime := switchTime
+		updateTime := st.WifiHop
 		if len(wm.devs) > 0 {
-			updateTime = watchDogTime
+			updateTime = st.WifiScan
 		}
 		select {
-		case <-time.After(updateTime):
+		case <-time.After(time.Duration(updateTime)):
+		case <-changed:
 		case <-ctx.Done():
 			return nil
 		}
@@ -79,13 +102,39 @@ func (wm *wifiMon) monDevs(ctx context.Context, s *store) error {
 	return nil
 }
 
-func wifiLogger(w *wlan.Wifi, s *store) (err error) {
-	defer func() {
-		if cerr := w.Close(); err == nil {
-			err = cerr
+// deadDevs are the devices the watchdogs gave up on, sorted by name.
+func (wm *wifiMon) deadDevs() (dead []string) {
+	wm.dogsMu.Lock()
+	defer wm.dogsMu.Unlock()
+	for n, wd := range wm.dogs {
+		if wd.dead() {
+			dead = append(dead, n)
 		}
-	}()
-	wdir, err := s.Wifi(w.Name())
+	}
+	sort.Strings(dead)
+	return dead
+}
+
+// captures counts the devices running tcpdump.
+func (wm *wifiMon) captures() (n int) {
+	wm.dogsMu.Lock()
+	defer wm.dogsMu.Unlock()
+	for _, wd := range wm.dogs {
+		if wd.capturing {
+			n++
+		}
+	}
+	return n
+}
+
+// errCaptureStalled restarts a capture whose device stopped receiving.
+var errCaptureStalled = errors.New("capture stalled")
+
+// wifiLogger captures from a wifi device, hopping channels, until the
+// capture fails or ctx is done. The watchdog, guarded by mu, recovers
+// the capture if the device stops receiving frames.
+func (d *daemon) wifiLogger(ctx context.Context, w *wlan.Wifi, wd *captureWatchdog, mu *sync.Mutex) (err error) {
+	wdir, err := d.s.Wifi(w.Name())
 	if err != nil {
 		return err
 	}
@@ -95,13 +144,29 @@ func wifiLogger(w *wlan.Wifi, s *store) (err error) {
 	if err = w.Monitor(); err != nil {
 		return err
 	}
+	defer func() {
+		if rerr := w.Restore(); rerr != nil {
+			log.Errorf("%s: restoring mode: %v", w.Name(), rerr)
+		}
+	}()
 	if err = w.Up(); err != nil {
 		return err
 	}
 
+	st, _ := d.settings()
 	log.Infof("%s: tcpdump to %s", w.Name(), wdir)
+	tctx, stop := context.WithCancel(ctx)
+	defer stop()
 	errc := make(chan error, 1)
-	go func() { errc <- w.Tcpdump(wdir) }()
+	go func() { errc <- w.Tcpdump(tctx, wdir, st.PCapSize) }()
+	mu.Lock()
+	wd.capturing = true
+	mu.Unlock()
+	defer func() {
+		mu.Lock()
+		wd.capturing = false
+		mu.Unlock()
+	}()
 
 	fs := w.Frequencies()
 	freqs := make([]int, 0, len(fs))
@@ -109,26 +174,71 @@ func wifiLogger(w *wlan.Wifi, s *store) (err error) {
 		freqs = append(freqs, mhz)
 	}
 
-	ticker := time.NewTicker(switchTime)
-	defer ticker.Stop()
 	fidx := 0
+	tune := func() {
+		var err error
+		for i := 0; i < len(freqs); i++ {
+			fidx = (fidx + 1) % len(freqs)
+			if err = w.Tune(freqs[fidx]); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			log.Errorf("%d: %v", freqs[fidx], err)
+		}
+	}
 	for {
+		st, changed := d.settings()
 		select {
-		case <-ticker.C:
-			for i := 0; i < len(freqs); i++ {
-				fidx = (fidx + 1) % len(freqs)
-				if err = w.Tune(freqs[fidx]); err == nil {
-					break
-				}
-			}
-			if err != nil {
-				log.Errorf("%d: %v", freqs[fidx], err)
+		case <-time.After(time.Duration(st.WifiHop)):
+			tune()
+		case <-changed:
+			continue
+		case err = <-errc:
+			if ctx.Err() != nil {
+				// Stopped; err is from gzipping the last capture.
+				return err
 			}
-		case <-w.Done():
-			err = <-errc
 			log.Errorf("%s: %v", w.Name(), err)
 			return err
 		}
+
+		n, err := w.RxPackets()
+		if err != nil {
+			log.Errorf("%s: %v", w.Name(), err)
+			continue
+		}
+		mu.Lock()
+		action := wd.check(n, time.Now(), time.Duration(st.WifiStall))
+		mu.Unlock()
+		if action == stallNone {
+			continue
+		}
+		log.Warnf("%s: no frames for %v; %v", w.Name(), time.Duration(st.WifiStall), action)
+		d.event("wifi/"+w.Name(), action.String(), fmt.Sprintf("rx_packets %d", n))
+		switch action {
+		case stallRetune:
+			tune()
+		case stallReset:
+			err = w.Down()
+			if err == nil {
+				err = w.Monitor()
+			}
+			if err == nil {
+				err = w.Up()
+			}
+			if err != nil {
+				log.Errorf("%s: reset: %v", w.Name(), err)
+			}
+		case stallRestart, stallDead:
+			stop()
+			if err := <-errc; err != nil {
+				log.Errorf("%s: %v", w.Name(), err)
+			}
+			if action == stallDead {
+				return nil
+			}
+			return errCaptureStalled
+		}
 	}
-	return nil
 }
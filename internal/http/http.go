@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/bikeos/bosd/gps"
 	"github.com/bikeos/bosd/ingest"
 )
 
@@ -13,6 +14,9 @@ type ServerConfig struct {
 	ListenAddr string
 	RootDir    string
 	LogDir     string
+	// GPSFilter filters the fixes joined with packets; nil joins raw
+	// fixes.
+	GPSFilter *gps.FilterConfig
 }
 
 type apiHandler struct {
@@ -50,7 +54,7 @@ func (ah *apiHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 
 func Serve(cfg ServerConfig) error {
 	tdb := ingest.NewTimeMapDB()
-	if err := tdb.AddTrips(cfg.LogDir); err != nil {
+	if err := tdb.AddTrips(cfg.LogDir, cfg.GPSFilter); err != nil {
 		return err
 	}
 	mux := http.NewServeMux()
@@ -4,12 +4,13 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/bikeos/bosd/gps"
 	"github.com/bikeos/bosd/ingest"
 )
 
-func Run(dir string) error {
+func Run(dir string, filter *gps.FilterConfig) error {
 	tdb := ingest.NewTimeMapDB()
-	if err := tdb.AddTrips(dir); err != nil {
+	if err := tdb.AddTrips(dir, filter); err != nil {
 		return err
 	}
 	fmt.Println("var mapFeatures = [")
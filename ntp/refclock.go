@@ -0,0 +1,101 @@
+package ntp
+
+import (
+	"strings"
+	"time"
+
+	"github.com/bikeos/bosd/gps"
+)
+
+// RefclockConfig configures GPS time samples. Zero values take
+// defaults.
+type RefclockConfig struct {
+	// Unit is the SHM unit the NTP daemon reads (e.g., 0 for chronyd's
+	// "refclock SHM 0").
+	Unit int
+	// Delay is the time from the start of a fix's second to the
+	// receiver sending its first sentence. It depends on the receiver;
+	// the NTP daemon's offset option can trim what is left.
+	Delay time.Duration
+	// Baud is the serial rate, for the time spent sending a sentence.
+	// Zero leaves out the send time, as for USB-ACM receivers, gpsd, or
+	// a rate that is not known.
+	Baud int
+	// Precision is the log2 of the sample precision in seconds;
+	// defaults to -1, as sentences jitter by tens of milliseconds.
+	Precision int
+}
+
+// sampleWriter publishes samples; an SHM segment outside of tests.
+type sampleWriter interface {
+	Write(Sample)
+	Close() error
+}
+
+// Refclock turns GPS fixes into samples for an NTP daemon.
+type Refclock struct {
+	cfg  RefclockConfig
+	w    sampleWriter
+	last time.Time
+}
+
+// NewRefclock opens the SHM unit for GPS time samples.
+func NewRefclock(cfg RefclockConfig) (*Refclock, error) {
+	shm, err := OpenSHM(cfg.Unit)
+	if err != nil {
+		return nil, err
+	}
+	return newRefclock(cfg, shm), nil
+}
+
+func newRefclock(cfg RefclockConfig, w sampleWriter) *Refclock {
+	if cfg.Precision == 0 {
+		cfg.Precision = -1
+	}
+	return &Refclock{cfg: cfg, w: w}
+}
+
+// Update writes a sample for the first message of each fix. Returns
+// true if it wrote a sample.
+func (r *Refclock) Update(msg gps.NMEA) bool {
+	smp, ok := r.sample(msg)
+	if ok {
+		r.w.Write(smp)
+	}
+	return ok
+}
+
+func (r *Refclock) sample(msg gps.NMEA) (Sample, bool) {
+	fix, recv := msg.Fix(), msg.Received()
+	if !msg.Valid() || fix.IsZero() || recv.IsZero() || fix.Equal(r.last) {
+		return Sample{}, false
+	}
+	// Later messages of a fix queue behind the first; only the first
+	// tells when the receiver started sending.
+	r.last = fix
+	return Sample{
+		Clock:     fix,
+		Receive:   recv.Add(-r.cfg.Delay - r.sendTime(msg)),
+		Precision: r.cfg.Precision,
+	}, true
+}
+
+// sendTime is the time spent sending a message over the serial line; a
+// message is read once its last byte arrives.
+func (r *Refclock) sendTime(msg gps.NMEA) time.Duration {
+	if r.cfg.Baud == 0 {
+		return 0
+	}
+	n := len(msg.UBX())
+	if l := msg.Line(); l != "" {
+		n = len(strings.TrimRight(l, "\r\n")) + len("\r\n")
+	}
+	// 8N1 sends ten bits a byte.
+	return time.Duration(n*10) * time.Second / time.Duration(r.cfg.Baud)
+}
+
+// Unit is the SHM unit of the samples.
+func (r *Refclock) Unit() int { return r.cfg.Unit }
+
+// Close closes the SHM unit.
+func (r *Refclock) Close() error { return r.w.Close() }
@@ -0,0 +1,97 @@
+package ntp
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/bikeos/bosd/gps"
+)
+
+type fakeSHM struct{ samples []Sample }
+
+func (f *fakeSHM) Write(smp Sample) { f.samples = append(f.samples, smp) }
+func (f *fakeSHM) Close() error     { return nil }
+
+func TestRefclock(t *testing.T) {
+	// Zero baud leaves out the send time.
+	for _, baud := range []int{4800, 0} {
+		testRefclock(t, baud)
+	}
+}
+
+func testRefclock(t *testing.T, baud int) {
+	start := time.Date(2018, 3, 12, 2, 55, 3, 0, time.UTC)
+	g, err := gps.NewSimGPS(gps.SimConfig{
+		Route: []gps.RoutePoint{
+			{Lat: 48.1173, Lon: 11.5167, Alt: math.NaN()},
+			{Lat: 48.1182, Lon: 11.5167, Alt: math.NaN()},
+		},
+		Speeds:    []gps.SimSpeed{{At: 0, Speed: 10}},
+		ColdStart: 3 * time.Second,
+		Start:     start,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	w := &fakeSHM{}
+	r := newRefclock(RefclockConfig{Delay: 100 * time.Millisecond, Baud: baud}, w)
+	var want []Sample
+	for msg := range g.NMEA() {
+		_, isRMC := msg.Msg().(*gps.RMC)
+		if r.Update(msg) != (isRMC && msg.Valid()) {
+			t.Fatalf("baud %d: expected samples for valid RMC only, got one for %q", baud, msg.Line())
+		}
+		if !isRMC || !msg.Valid() {
+			continue
+		}
+		// Simulated RMC sentences are 69 bytes sent with CRLF.
+		if n := len(msg.Line()); n != 68 {
+			t.Fatalf("expected 68 byte line, got %q", msg.Line())
+		}
+		var send time.Duration
+		if baud != 0 {
+			send = 69 * 10 * time.Second / time.Duration(baud)
+		}
+		want = append(want, Sample{
+			Clock:     msg.Fix(),
+			Receive:   msg.Received().Add(-100*time.Millisecond - send),
+			Precision: -1,
+		})
+	}
+	if len(want) != 9 {
+		t.Fatalf("baud %d: expected 9 fixes after the cold start, got %d", baud, len(want))
+	}
+	if len(w.samples) != len(want) {
+		t.Fatalf("baud %d: expected %d samples, got %d", baud, len(want), len(w.samples))
+	}
+	for i := range want {
+		if got := w.samples[i]; !got.Clock.Equal(want[i].Clock) || !got.Receive.Equal(want[i].Receive) || got.Precision != -1 {
+			t.Errorf("baud %d #%d: wanted %+v, got %+v", baud, i, want[i], got)
+		}
+	}
+	if !w.samples[0].Clock.Equal(start.Add(3 * time.Second)) {
+		t.Errorf("expected first sample at %v, got %v", start.Add(3*time.Second), w.samples[0].Clock)
+	}
+}
+
+func TestRefclockRepeatedFix(t *testing.T) {
+	g, err := gps.NewSimGPS(gps.SimConfig{
+		Route: []gps.RoutePoint{{Lat: 0, Lon: 0, Alt: math.NaN()}, {Lat: 0, Lon: 0.0001, Alt: math.NaN()}},
+		Rate:  1,
+		Start: time.Date(2018, 3, 12, 2, 55, 3, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	msg := <-g.NMEA()
+	r := newRefclock(RefclockConfig{}, &fakeSHM{})
+	if !r.Update(msg) {
+		t.Fatalf("expected sample for %q", msg.Line())
+	}
+	if r.Update(msg) {
+		t.Errorf("expected no sample for the same fix")
+	}
+}
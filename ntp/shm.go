@@ -0,0 +1,31 @@
+// Package ntp feeds GPS time to chronyd or ntpd through the shared
+// memory reference clock driver (SHM).
+package ntp
+
+import "time"
+
+// shmKey is the SysV IPC key of SHM unit 0; unit n is at shmKey+n.
+const shmKey = 0x4e545030 // "NTP0"
+
+// Sample is a reading of the reference clock.
+type Sample struct {
+	// Clock is the time told by the reference clock.
+	Clock time.Time
+	// Receive is the system time when the reference clock told Clock.
+	Receive time.Time
+	// Leap is the NTP leap indicator; zero if no leap second is due.
+	Leap int
+	// Precision is the log2 of the sample precision in seconds.
+	Precision int
+}
+
+// SHM is an attached shared memory segment of an NTP refclock unit.
+// Units 0 and 1 are only accessible to root, as ntpd expects; higher
+// units are world writable.
+type SHM struct {
+	unit int
+	seg  *shmTime
+}
+
+// Unit is the refclock unit of the segment.
+func (s *SHM) Unit() int { return s.unit }
@@ -0,0 +1,85 @@
+// +build linux,amd64 linux,arm linux,arm64
+
+package ntp
+
+import (
+	"fmt"
+	"sync/atomic"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// ipcCreat is IPC_CREAT from sys/ipc.h.
+const ipcCreat = 01000
+
+// shmTime is struct shmTime from ntpd's refclock_shm.c. A C long,
+// and so time_t, is a Go int on linux.
+type shmTime struct {
+	mode                 int32
+	count                int32
+	clockTimeStampSec    int
+	clockTimeStampUSec   int32
+	receiveTimeStampSec  int
+	receiveTimeStampUSec int32
+	leap                 int32
+	precision            int32
+	nsamples             int32
+	valid                int32
+	clockTimeStampNSec   uint32
+	receiveTimeStampNSec uint32
+	dummy                [8]int32
+}
+
+// OpenSHM attaches to the segment of a refclock unit, creating it if
+// the NTP daemon has not.
+func OpenSHM(unit int) (*SHM, error) {
+	perm := 0666
+	if unit < 2 {
+		perm = 0600
+	}
+	id, _, errno := unix.Syscall(unix.SYS_SHMGET, uintptr(shmKey+unit),
+		unsafe.Sizeof(shmTime{}), uintptr(ipcCreat|perm))
+	if errno != 0 {
+		return nil, fmt.Errorf("ntp: shm unit %d: %v", unit, errno)
+	}
+	addr, _, errno := unix.Syscall(unix.SYS_SHMAT, id, 0, 0)
+	if errno != 0 {
+		return nil, fmt.Errorf("ntp: attaching shm unit %d: %v", unit, errno)
+	}
+	// Convert through a pointer so vet sees no uintptr arithmetic.
+	seg := *(**shmTime)(unsafe.Pointer(&addr))
+	return &SHM{unit: unit, seg: seg}, nil
+}
+
+// Write publishes a sample for the NTP daemon to read. It follows the
+// mode 1 protocol: readers discard samples torn by a concurrent write.
+func (s *SHM) Write(smp Sample) {
+	seg := s.seg
+	atomic.StoreInt32(&seg.valid, 0)
+	atomic.AddInt32(&seg.count, 1)
+	seg.mode = 1
+	seg.clockTimeStampSec = int(smp.Clock.Unix())
+	seg.clockTimeStampUSec = int32(smp.Clock.Nanosecond() / 1e3)
+	seg.clockTimeStampNSec = uint32(smp.Clock.Nanosecond())
+	seg.receiveTimeStampSec = int(smp.Receive.Unix())
+	seg.receiveTimeStampUSec = int32(smp.Receive.Nanosecond() / 1e3)
+	seg.receiveTimeStampNSec = uint32(smp.Receive.Nanosecond())
+	seg.leap = int32(smp.Leap)
+	seg.precision = int32(smp.Precision)
+	atomic.AddInt32(&seg.count, 1)
+	atomic.StoreInt32(&seg.valid, 1)
+}
+
+// Close detaches the segment; the segment lives on for the NTP daemon.
+func (s *SHM) Close() error {
+	if s.seg == nil {
+		return nil
+	}
+	_, _, errno := unix.Syscall(unix.SYS_SHMDT, uintptr(unsafe.Pointer(s.seg)), 0, 0)
+	s.seg = nil
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
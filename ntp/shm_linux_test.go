@@ -0,0 +1,50 @@
+// +build linux,amd64 linux,arm linux,arm64
+
+package ntp
+
+import (
+	"os"
+	"testing"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// ipcRmid is IPC_RMID from sys/ipc.h.
+const ipcRmid = 0
+
+func TestSHM(t *testing.T) {
+	// Use a unit no NTP daemon is reading.
+	unit := 100 + os.Getpid()%1000
+	s, err := OpenSHM(unit)
+	if err != nil {
+		t.Skip(err)
+	}
+	id, _, _ := unix.Syscall(unix.SYS_SHMGET, uintptr(shmKey+unit), 0, 0)
+	defer unix.Syscall(unix.SYS_SHMCTL, id, ipcRmid, 0)
+	defer s.Close()
+
+	clock := time.Date(2018, 3, 12, 2, 55, 3, 0, time.UTC)
+	recv := clock.Add(1234567891 * time.Nanosecond)
+	s.Write(Sample{Clock: clock, Receive: recv, Precision: -1})
+	s.Write(Sample{Clock: clock.Add(time.Second), Receive: recv.Add(time.Second), Precision: -1})
+
+	if size := unsafe.Sizeof(shmTime{}); size != 96 && size != 80 {
+		t.Errorf("expected the 64-bit or 32-bit C layout, got %d bytes", size)
+	}
+	seg := *s.seg
+	want := shmTime{
+		mode:                 1,
+		count:                4,
+		clockTimeStampSec:    int(clock.Unix()) + 1,
+		receiveTimeStampSec:  int(recv.Unix()) + 1,
+		receiveTimeStampUSec: 234567,
+		receiveTimeStampNSec: 234567891,
+		precision:            -1,
+		valid:                1,
+	}
+	if seg != want {
+		t.Errorf("wanted %+v, got %+v", want, seg)
+	}
+}
@@ -0,0 +1,18 @@
+// +build !linux linux,!amd64,!arm,!arm64
+
+package ntp
+
+import "errors"
+
+type shmTime struct{}
+
+// OpenSHM attaches to the segment of a refclock unit.
+func OpenSHM(unit int) (*SHM, error) {
+	return nil, errors.New("ntp: shm refclock not supported")
+}
+
+// Write publishes a sample for the NTP daemon to read.
+func (s *SHM) Write(smp Sample) {}
+
+// Close detaches the segment.
+func (s *SHM) Close() error { return nil }
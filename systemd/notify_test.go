@@ -0,0 +1,64 @@
+package systemd
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestNotify(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	if ok, err := Notify("READY=1"); ok || err != nil {
+		t.Fatalf("without socket: got %v, %v", ok, err)
+	}
+
+	sock := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: sock, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	t.Setenv("NOTIFY_SOCKET", sock)
+	for _, state := range []string{"READY=1", "STATUS=GPS fix\nWATCHDOG=1"} {
+		if ok, err := Notify(state); !ok || err != nil {
+			t.Fatalf("%q: got %v, %v", state, ok, err)
+		}
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		b := make([]byte, 256)
+		n, err := conn.Read(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(b[:n]); got != state {
+			t.Errorf("got %q, want %q", got, state)
+		}
+	}
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	pid := strconv.Itoa(os.Getpid())
+	var tts = []struct {
+		usec string
+		pid  string
+
+		want time.Duration
+		err  bool
+	}{
+		{"", "", 0, false},
+		{"30000000", "", 30 * time.Second, false},
+		{"30000000", pid, 30 * time.Second, false},
+		{"30000000", "1", 0, false},
+		{"x", "", 0, true},
+	}
+	for i, tt := range tts {
+		t.Setenv("WATCHDOG_USEC", tt.usec)
+		t.Setenv("WATCHDOG_PID", tt.pid)
+		got, err := WatchdogInterval()
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("%d: got %v, %v; want %v, error %v", i, got, err, tt.want, tt.err)
+		}
+	}
+}
@@ -20,6 +20,12 @@ func (p Packet) Time() time.Time { return p.t }
 func (p Packet) Src() string     { return p.srcAddr }
 func (p Packet) Dst() string     { return p.dstAddr }
 
+// Shift moves the time of the packet by an offset.
+func (p Packet) Shift(d time.Duration) Packet {
+	p.t = p.t.Add(d)
+	return p
+}
+
 func NewPCapFileChan(pcapFile string) (<-chan Packet, error) {
 	if _, err := os.Stat(pcapFile); os.IsNotExist(err) {
 		return nil, err
@@ -1,22 +1,34 @@
 package wlan
 
 import (
+	"compress/gzip"
 	"context"
 	"io"
+	"io/ioutil"
+	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	nl80211 "github.com/mdlayher/wifi"
 )
 
 type Wifi struct {
-	iface   *nl80211.Interface
+	iface *nl80211.Interface
+	// mode is the interface type to restore.
+	mode    nl80211.InterfaceType
 	tcpdump *exec.Cmd
 	donec   chan struct{}
 }
 
+// tcpdumpStopTime is how long tcpdump has to flush its capture once
+// interrupted before it is killed.
+var tcpdumpStopTime = 3 * time.Second
+
 func NewWifi(dev Device) (*Wifi, error) {
-	return &Wifi{dev.iface, nil, nil}, nil
+	return &Wifi{iface: dev.iface, mode: dev.iface.Type}, nil
 }
 
 func (w *Wifi) Name() string { return w.iface.Name }
@@ -40,18 +52,89 @@ func (w *Wifi) Done() <-chan struct{} {
 	return w.donec
 }
 
-// Tcpdump writes interface data to a given directory.
-func (w *Wifi) Tcpdump(logdir string) error {
+// Tcpdump writes interface data to a given directory, starting a new
+// file every fileMB megabytes, until ctx is done. Finished files are
+// gzipped, including the last one once ctx is done.
+func (w *Wifi) Tcpdump(ctx context.Context, logdir string, fileMB int) error {
 	defer func() { close(w.donec) }()
 	w.donec = make(chan struct{})
-	w.tcpdump = exec.Command(
+	cmd := exec.CommandContext(ctx,
 		"/usr/sbin/tcpdump",
 		"-i", w.iface.Name,
 		"-w", filepath.Join(logdir, "pcap"),
-		"-C", "4",
+		"-C", strconv.Itoa(fileMB),
 		"-z", "gzip",
 	)
-	return w.tcpdump.Run()
+	// Interrupt instead of killing so tcpdump flushes the last file.
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	cmd.WaitDelay = tcpdumpStopTime
+	w.tcpdump = cmd
+	err := cmd.Run()
+	if ctx.Err() == nil {
+		return err
+	}
+	// tcpdump only gzips files it rotates away from.
+	return gzipLast(logdir, "pcap")
+}
+
+// gzipLast compresses the newest of the files rotated by tcpdump -C.
+func gzipLast(dir, prefix string) error {
+	names, err := filepath.Glob(filepath.Join(dir, prefix+"*"))
+	if err != nil {
+		return err
+	}
+	last, lastn := "", -1
+	for _, name := range names {
+		if strings.HasSuffix(name, ".gz") {
+			continue
+		}
+		// Files are numbered after the first: pcap, pcap1, pcap2, ...
+		n := 0
+		if suffix := strings.TrimPrefix(filepath.Base(name), prefix); suffix != "" {
+			if n, err = strconv.Atoi(suffix); err != nil {
+				continue
+			}
+		}
+		if n > lastn {
+			last, lastn = name, n
+		}
+	}
+	if last == "" {
+		return nil
+	}
+	return gzipFile(last)
+}
+
+// gzipFile replaces a file with its gzipped copy, as gzip(1) does.
+func gzipFile(p string) (err error) {
+	in, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(p + ".gz")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(p + ".gz")
+		}
+	}()
+	zw := gzip.NewWriter(out)
+	if _, err = io.Copy(zw, in); err != nil {
+		return err
+	}
+	if err = zw.Close(); err != nil {
+		return err
+	}
+	if err = out.Sync(); err != nil {
+		return err
+	}
+	return os.Remove(p)
 }
 
 func (w *Wifi) TcpdumpReader(ctx context.Context) (io.Reader, error) {
@@ -73,6 +156,34 @@ func (w *Wifi) Monitor() error {
 	return c.SetInterface(w.iface, nl80211.InterfaceTypeMonitor)
 }
 
+// RxPackets is the number of frames the device has received.
+func (w *Wifi) RxPackets() (uint64, error) {
+	b, err := ioutil.ReadFile(filepath.Join("/sys/class/net", w.iface.Name, "statistics/rx_packets"))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+}
+
+// Restore puts the wifi device back in the mode it had before Monitor.
+func (w *Wifi) Restore() error {
+	if w.mode == nl80211.InterfaceTypeUnspecified || w.mode == nl80211.InterfaceTypeMonitor {
+		return nil
+	}
+	c, err := nl80211.New()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if err := w.Down(); err != nil {
+		return err
+	}
+	if err := c.SetInterface(w.iface, w.mode); err != nil {
+		return err
+	}
+	return w.Up()
+}
+
 // Tune sets the frequency to some given mhz.
 func (w *Wifi) Tune(mhz int) error {
 	c, err := nl80211.New()
@@ -0,0 +1,51 @@
+package wlan
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGzipLast(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"pcap":     "first",
+		"pcap1.gz": "second",
+		"pcap2":    "third",
+		"pcap10":   "last",
+	}
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := gzipLast(dir, "pcap"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"pcap", "pcap2"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "pcap10")); !os.IsNotExist(err) {
+		t.Errorf("pcap10 not removed: %v", err)
+	}
+	f, err := os.Open(filepath.Join(dir, "pcap10.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "last" {
+		t.Errorf("got %q, want %q", b, "last")
+	}
+}